@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"log/slog"
@@ -9,6 +10,8 @@ import (
 	"split-the-bill/internal/controllers"
 	"split-the-bill/internal/middleware"
 	"split-the-bill/internal/routes"
+	"split-the-bill/internal/scheduler"
+	"split-the-bill/internal/storage"
 	"time"
 )
 
@@ -33,15 +36,23 @@ func main() {
 		os.Exit(1)
 	}
 
+	store, err := storage.FromEnv()
+	if err != nil {
+		log.Error("Failed to init attachment storage", "error", err)
+		os.Exit(1)
+	}
+
 	r.POST("/login", controllers.LoginHandler(db, jwtSecret))
 	r.POST("/register", controllers.RegisterHandler(db, jwtSecret))
 
+	go scheduler.New(db, log, time.Minute, controllers.CreateOccurrence).Run(context.Background())
+
 	authorized := r.Group("/")
 
 	authorized.Use(middleware.AuthMiddleware(jwtSecret, db, log))
 
-	routes.SetupRoutes(authorized, db)
-	err := r.Run(":8080")
+	routes.SetupRoutes(authorized, db, store)
+	err = r.Run(":8080")
 	if err != nil {
 		log.Error("Error starting server")
 		os.Exit(1)
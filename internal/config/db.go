@@ -7,6 +7,27 @@ import (
 	"split-the-bill/internal/models"
 )
 
+// Models — все таблицы приложения в порядке миграции.
+var Models = []interface{}{
+	&models.User{},
+	&models.UserPaymentDetails{},
+	&models.Event{},
+	&models.EventParticipant{},
+	&models.Expense{},
+	&models.ExpenseShare{},
+	&models.ExpenseItem{},
+	&models.ExpenseItemAssignment{},
+	&models.ExpenseAdjustment{},
+	&models.Attachment{},
+	&models.Debt{},
+	&models.Payment{},
+	&models.PaymentStatusChange{},
+	&models.Settlement{},
+	&models.EventInvite{},
+	&models.RecurringExpense{},
+	&models.RecurringExpenseShare{},
+}
+
 func InitDB() *gorm.DB {
 	dsn := "host=localhost user=user password=password dbname=splitwise_db port=5432 sslmode=disable"
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
@@ -14,19 +35,19 @@ func InitDB() *gorm.DB {
 		log.Fatal("Failed to connect to DB:", err)
 	}
 
-	err = db.AutoMigrate(
-		&models.User{},
-		&models.Event{},
-		&models.EventParticipant{},
-		&models.Expense{},
-		&models.ExpenseShare{},
-		&models.Debt{},
-		&models.Payment{},
-	)
+	if err := db.Transaction(migrateMoneyColumns); err != nil {
+		log.Fatal("Failed to migrate money columns:", err)
+	}
+
+	err = db.AutoMigrate(Models...)
 	if err != nil {
 		log.Fatal("Failed to migrate DB:", err)
 		return nil
 	}
 
+	if err := assignEventOwners(db); err != nil {
+		log.Fatal("Failed to assign event owners:", err)
+	}
+
 	return db
 }
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"split-the-bill/internal/models"
+	"strings"
+)
+
+// moneyColumns — колонки, которые раньше хранили суммы в рублях (NUMERIC/float)
+// и теперь хранят целые копейки.
+var moneyColumns = []struct {
+	table  string
+	column string
+}{
+	{"expenses", "amount"},
+	{"expense_shares", "share_amount"},
+	{"debts", "amount"},
+	{"payments", "amount"},
+}
+
+// migrateMoneyColumns переводит существующие дробные суммы в копейки до AutoMigrate,
+// иначе тот сменил бы тип колонки простым приведением и потерял бы копейки.
+func migrateMoneyColumns(db *gorm.DB) error {
+	m := db.Migrator()
+	for _, mc := range moneyColumns {
+		if !m.HasTable(mc.table) || !m.HasColumn(mc.table, mc.column) {
+			continue
+		}
+
+		columnTypes, err := m.ColumnTypes(mc.table)
+		if err != nil {
+			return fmt.Errorf("%s: %w", mc.table, err)
+		}
+
+		for _, ct := range columnTypes {
+			if ct.Name() != mc.column {
+				continue
+			}
+			switch strings.ToLower(ct.DatabaseTypeName()) {
+			case "int8", "bigint":
+				continue
+			}
+
+			query := fmt.Sprintf(
+				"ALTER TABLE %s ALTER COLUMN %s TYPE BIGINT USING ROUND(%s * 100)::BIGINT",
+				mc.table, mc.column, mc.column,
+			)
+			if err := db.Exec(query).Error; err != nil {
+				return fmt.Errorf("%s.%s: %w", mc.table, mc.column, err)
+			}
+		}
+	}
+	return nil
+}
+
+// assignEventOwners делает создателя владельцем в событиях, где владельца ещё нет
+// (события, созданные до появления ролей).
+func assignEventOwners(db *gorm.DB) error {
+	return db.Exec(`
+		UPDATE event_participants p SET role = ?
+		FROM events e
+		WHERE e.id = p.event_id AND e.created_by = p.user_id
+		  AND NOT EXISTS (
+		      SELECT 1 FROM event_participants o
+		      WHERE o.event_id = e.id AND o.role = ?
+		  )`, models.RoleOwner, models.RoleOwner).Error
+}
@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+	"split-the-bill/internal/split"
+)
+
+// AdjustmentInput — налог, чаевые, сервисный сбор или скидка: percent от
+// подытога или фиксированная amount. distribution — proportional (по умолчанию)
+// или equal.
+type AdjustmentInput struct {
+	Kind         split.AdjustmentKind `json:"kind"`
+	Percent      money.Percent        `json:"percent"`
+	Amount       money.Money          `json:"amount"`
+	Distribution split.Distribution   `json:"distribution"`
+}
+
+func (in AdjustmentInput) adjustment() split.Adjustment {
+	distribution := in.Distribution
+	if distribution == "" {
+		distribution = split.DistributeProportional
+	}
+	return split.Adjustment{
+		Kind:         in.Kind,
+		Percent:      in.Percent,
+		Amount:       in.Amount,
+		Distribution: distribution,
+	}
+}
+
+// resolveTotals считает подытог и итоговую сумму траты с надбавками.
+// Подытог берётся из subtotal или, если он не задан, из позиций чека;
+// amount, если указан, должен совпасть с подытогом плюс надбавки.
+func resolveTotals(input *CreateExpenseInput, itemsTotal money.Money) error {
+	subtotal := input.Subtotal
+	if subtotal == 0 && len(input.Items) > 0 {
+		subtotal = itemsTotal
+	}
+	if subtotal <= 0 {
+		return fmt.Errorf("%w: для надбавок нужен положительный subtotal", split.ErrInvalid)
+	}
+
+	total := subtotal
+	for _, in := range input.Adjustments {
+		a := in.adjustment()
+		if err := a.Validate(); err != nil {
+			return err
+		}
+		total += a.Resolve(subtotal)
+	}
+	if total <= 0 {
+		return fmt.Errorf("%w: скидка не может превышать подытог", split.ErrInvalid)
+	}
+	if input.Amount != 0 && input.Amount != total {
+		return fmt.Errorf("%w: amount %s не совпадает с подытогом и надбавками (%s)", split.ErrInvalid, input.Amount, total)
+	}
+
+	input.Subtotal = subtotal
+	input.Amount = total
+	return nil
+}
+
+// applyAdjustments раскладывает надбавки по долям и записывает разбивку в каждую долю.
+func applyAdjustments(shares []models.ExpenseShare, inputs []AdjustmentInput, rem split.Remainder) error {
+	base := make([]split.Share, len(shares))
+	for i, s := range shares {
+		base[i] = split.Share{UserID: s.UserID, Amount: s.ShareAmount}
+	}
+	adjustments := make([]split.Adjustment, len(inputs))
+	for i, in := range inputs {
+		adjustments[i] = in.adjustment()
+	}
+
+	breakdown, err := split.Adjust(base, adjustments, rem)
+	if err != nil {
+		return err
+	}
+	for i, b := range breakdown {
+		shares[i].ShareAmount = b.Total
+		shares[i].Subtotal = b.Subtotal
+		shares[i].Tax = b.Adjustments[split.KindTax]
+		shares[i].Tip = b.Adjustments[split.KindTip]
+		shares[i].Service = b.Adjustments[split.KindService]
+		shares[i].Discount = b.Adjustments[split.KindDiscount]
+	}
+	return nil
+}
+
+// replaceAdjustments заменяет надбавки траты; subtotal — подытог, от которого
+// считаются процентные надбавки.
+func replaceAdjustments(tx *gorm.DB, expense *models.Expense, inputs []AdjustmentInput, subtotal money.Money) error {
+	if err := tx.Where("expense_id = ?", expense.ID).Delete(&models.ExpenseAdjustment{}).Error; err != nil {
+		return err
+	}
+
+	adjustments := make([]models.ExpenseAdjustment, len(inputs))
+	for i, in := range inputs {
+		a := in.adjustment()
+		adjustments[i] = models.ExpenseAdjustment{
+			ExpenseID:    expense.ID,
+			Kind:         string(a.Kind),
+			Percent:      a.Percent,
+			Amount:       a.Amount,
+			Distribution: string(a.Distribution),
+			Total:        a.Resolve(subtotal),
+		}
+	}
+	if len(adjustments) > 0 {
+		if err := tx.Create(&adjustments).Error; err != nil {
+			return err
+		}
+	}
+	expense.Adjustments = adjustments
+	return nil
+}
+
+func adjustmentInputs(adjustments []models.ExpenseAdjustment) []AdjustmentInput {
+	inputs := make([]AdjustmentInput, len(adjustments))
+	for i, a := range adjustments {
+		inputs[i] = AdjustmentInput{
+			Kind:         split.AdjustmentKind(a.Kind),
+			Percent:      a.Percent,
+			Amount:       a.Amount,
+			Distribution: split.Distribution(a.Distribution),
+		}
+	}
+	return inputs
+}
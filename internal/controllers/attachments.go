@@ -0,0 +1,238 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"split-the-bill/internal/middleware"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/storage"
+	"split-the-bill/internal/thumbnail"
+	"strings"
+)
+
+const (
+	maxAttachmentSize        = 10 << 20
+	maxAttachmentsPerExpense = 20
+	thumbnailSize            = 320
+)
+
+// Тип файла определяется по содержимому, а не по заголовку клиента.
+var attachmentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+}
+
+// UploadAttachment принимает файл в multipart-поле file. Для изображений
+// дополнительно сохраняется миниатюра.
+func UploadAttachment(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expense, _ := middleware.Expense(c)
+		if !canEditExpense(c, expense) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Участник может прикреплять файлы только к своим тратам"})
+			return
+		}
+
+		// Запас на заголовки multipart сверх самого файла.
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAttachmentSize+1<<20)
+		header, err := c.FormFile("file")
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || (err == nil && header.Size > maxAttachmentSize) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Файл больше %d МБ", maxAttachmentSize>>20)})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Нужен файл в поле file"})
+			return
+		}
+
+		var count int64
+		if err := db.Model(&models.Attachment{}).Where("expense_id = ?", expense.ID).Count(&count).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при загрузке файла"})
+			return
+		}
+		if count >= maxAttachmentsPerExpense {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("К трате можно прикрепить не больше %d файлов", maxAttachmentsPerExpense)})
+			return
+		}
+
+		data, err := readFormFile(header)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Не удалось прочитать файл"})
+			return
+		}
+		contentType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+		if !attachmentTypes[contentType] {
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Поддерживаются только изображения JPEG, PNG, GIF, WebP и PDF"})
+			return
+		}
+
+		attachment := models.Attachment{
+			ExpenseID:   expense.ID,
+			FileName:    filepath.Base(header.Filename),
+			ContentType: contentType,
+			Size:        int64(len(data)),
+			UploadedBy:  c.GetUint("user_id"),
+		}
+		ctx := c.Request.Context()
+		if err := putAttachment(ctx, store, &attachment, data); err != nil {
+			deleteAttachmentFiles(ctx, store, attachment)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при сохранении файла"})
+			return
+		}
+		if err := db.Create(&attachment).Error; err != nil {
+			deleteAttachmentFiles(ctx, store, attachment)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при сохранении файла"})
+			return
+		}
+		c.JSON(http.StatusCreated, attachment)
+	}
+}
+
+func ListAttachments(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		expense, _ := middleware.Expense(c)
+		var attachments []models.Attachment
+		db.Where("expense_id = ?", expense.ID).Order("id").Find(&attachments)
+		c.JSON(http.StatusOK, attachments)
+	}
+}
+
+// DownloadAttachment отдаёт файл; с ?thumbnail=true — его миниатюру.
+func DownloadAttachment(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		attachment, ok := loadAttachment(c, db)
+		if !ok {
+			return
+		}
+
+		key, contentType, size := attachment.StorageKey, attachment.ContentType, attachment.Size
+		if c.Query("thumbnail") == "true" {
+			if !attachment.HasThumbnail {
+				c.JSON(http.StatusNotFound, gin.H{"error": "У файла нет миниатюры"})
+				return
+			}
+			key, contentType, size = attachment.ThumbnailKey, thumbnail.ContentType, -1
+		}
+
+		r, err := store.Get(c.Request.Context(), key)
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Файл не найден в хранилище"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при чтении файла"})
+			return
+		}
+		defer r.Close()
+
+		c.DataFromReader(http.StatusOK, size, contentType, r, map[string]string{
+			"Content-Disposition":    mime.FormatMediaType("inline", map[string]string{"filename": attachment.FileName}),
+			"X-Content-Type-Options": "nosniff",
+		})
+	}
+}
+
+// DeleteAttachment удаляет файл; права те же, что на редактирование траты,
+// плюс загрузивший файл участник.
+func DeleteAttachment(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		attachment, ok := loadAttachment(c, db)
+		if !ok {
+			return
+		}
+		expense, _ := middleware.Expense(c)
+		if !canEditOwn(c, attachment.UploadedBy, expense.CreatedBy, expense.PaidBy) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Участник может удалять только свои файлы"})
+			return
+		}
+
+		if err := db.Delete(&attachment).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении файла"})
+			return
+		}
+		deleteAttachmentFiles(c.Request.Context(), store, attachment)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func loadAttachment(c *gin.Context, db *gorm.DB) (models.Attachment, bool) {
+	expense, _ := middleware.Expense(c)
+	var attachment models.Attachment
+	err := db.Where("expense_id = ?", expense.ID).First(&attachment, c.Param("attachment_id")).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		return attachment, false
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при загрузке файла"})
+		return attachment, false
+	}
+	return attachment, true
+}
+
+func readFormFile(header *multipart.FileHeader) ([]byte, error) {
+	f, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, maxAttachmentSize))
+}
+
+// putAttachment кладёт файл и, для изображений, миниатюру в хранилище.
+// Изображение, которое не удалось декодировать, сохраняется без миниатюры.
+func putAttachment(ctx context.Context, store storage.Storage, attachment *models.Attachment, data []byte) error {
+	key, err := newAttachmentKey(attachment.ExpenseID)
+	if err != nil {
+		return err
+	}
+	if err := store.Put(ctx, key, bytes.NewReader(data), int64(len(data)), attachment.ContentType); err != nil {
+		return err
+	}
+	attachment.StorageKey = key
+
+	if !strings.HasPrefix(attachment.ContentType, "image/") {
+		return nil
+	}
+	thumb, err := thumbnail.Make(data, thumbnailSize)
+	if err != nil {
+		return nil
+	}
+	if err := store.Put(ctx, key+"-thumb", bytes.NewReader(thumb), int64(len(thumb)), thumbnail.ContentType); err != nil {
+		return err
+	}
+	attachment.ThumbnailKey = key + "-thumb"
+	attachment.HasThumbnail = true
+	return nil
+}
+
+// deleteAttachmentFiles удаляет файлы вложений из хранилища. Ошибки
+// игнорируются: запись о вложении уже удалена, а осиротевший файл безвреден.
+func deleteAttachmentFiles(ctx context.Context, store storage.Storage, attachments ...models.Attachment) {
+	for _, a := range attachments {
+		for _, key := range []string{a.StorageKey, a.ThumbnailKey} {
+			if key != "" {
+				_ = store.Delete(ctx, key)
+			}
+		}
+	}
+}
+
+func newAttachmentKey(expenseID uint) (string, error) {
+	b := make([]byte, 15)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("expenses/%d/%s", expenseID, base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)), nil
+}
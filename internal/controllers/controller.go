@@ -7,24 +7,62 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"math/rand"
 	"net/http"
+	"sort"
 	"split-the-bill/internal/common"
+	"split-the-bill/internal/ledger"
+	"split-the-bill/internal/middleware"
 	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+	"split-the-bill/internal/split"
+	"split-the-bill/internal/storage"
 	"strconv"
 	"time"
 )
 
 type ShareInput struct {
-	UserID      uint    `json:"user_id"`
-	ShareAmount float64 `json:"share_amount"`
+	UserID      uint          `json:"user_id"`
+	ShareAmount money.Money   `json:"share_amount"`
+	Percent     money.Percent `json:"percent"`
+	Weight      int64         `json:"weight"`
 }
 
+// CreateExpenseInput описывает трату. SplitMode задаёт, как трактовать Shares:
+// exact — точные суммы, percentage — проценты, weights — веса, equal — поровну
+// между перечисленными, except — поровну между всеми, кроме перечисленных,
+// itemized — доли считаются по позициям чека Items (amount можно не указывать).
+// Без SplitMode и Shares трата делится поровну между всеми участниками события.
+// С Adjustments доли считаются от Subtotal, а надбавки раскладываются поверх них;
+// amount тогда равен подытогу плюс надбавки и его можно не указывать.
 type CreateExpenseInput struct {
-	Title  string       `json:"title"`
-	Amount float64      `json:"amount"`
-	PaidBy uint         `json:"paid_by"`
-	PaidAt *time.Time   `json:"paid_at"`
-	Shares []ShareInput `json:"shares"`
+	Title       string            `json:"title"`
+	Amount      money.Money       `json:"amount"`
+	Subtotal    money.Money       `json:"subtotal"`
+	PaidBy      uint              `json:"paid_by"`
+	PaidAt      *time.Time        `json:"paid_at"`
+	SplitMode   split.Mode        `json:"split_mode"`
+	Shares      []ShareInput      `json:"shares"`
+	Items       []ItemInput       `json:"items"`
+	Adjustments []AdjustmentInput `json:"adjustments"`
+}
+
+var (
+	errForbidden         = errors.New("forbidden")
+	errNeedsConfirmation = errors.New("needs confirmation")
+)
+
+// isDuplicateKey сообщает, что err — нарушение уникального индекса. Ошибки
+// драйвера переводятся только здесь, остальные обработчики видят их как есть.
+func isDuplicateKey(db *gorm.DB, err error) bool {
+	if err == nil {
+		return false
+	}
+	if t, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		err = t.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
 }
 
 type SSORequest struct {
@@ -44,14 +82,42 @@ func GetUserID(c *gin.Context) (uint, error) {
 	return userID, nil
 }
 
+// CreateUserInput — поля, которые можно задать при создании пользователя.
+// Гостевой статус, права администратора и id через API не выставляются.
+type CreateUserInput struct {
+	Name     string  `json:"name"`
+	Email    *string `json:"email" binding:"omitempty,email"`
+	Password string  `json:"password"`
+}
+
 func CreateUser(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var user models.User
-		if err := c.ShouldBindJSON(&user); err != nil {
+		var input CreateUserInput
+		if err := c.ShouldBindJSON(&input); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		db.Create(&user)
+
+		user := models.User{Name: input.Name, Email: input.Email}
+		if input.Password != "" {
+			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
+				return
+			}
+			user.Password = string(hashedPassword)
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			return attachPendingInvites(tx, user)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+			return
+		}
 		c.JSON(http.StatusOK, user)
 	}
 }
@@ -123,7 +189,13 @@ func RegisterHandler(db *gorm.DB, jwtSecret string) gin.HandlerFunc {
 			Password: string(hashedPassword),
 		}
 
-		if err := db.Create(&user).Error; err != nil {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+			return attachPendingInvites(tx, user)
+		})
+		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
 			return
 		}
@@ -153,6 +225,20 @@ func CreateEvent(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 		event.CreatedBy = userID
+		if event.RemainderPolicy == "" {
+			event.RemainderPolicy = string(split.DefaultPolicy)
+		}
+		if !split.Policy(event.RemainderPolicy).Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестное правило распределения остатка"})
+			return
+		}
+		if event.Currency == "" {
+			event.Currency = string(money.DefaultCurrency)
+		}
+		if !money.Currency(event.Currency).Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Валюта должна быть кодом ISO 4217 с двумя знаками после запятой, например RUB"})
+			return
+		}
 		if err := db.Create(&event).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании события"})
 			return
@@ -160,6 +246,7 @@ func CreateEvent(db *gorm.DB) gin.HandlerFunc {
 		p := models.EventParticipant{
 			UserID:  userID,
 			EventID: event.ID,
+			Role:    models.RoleOwner,
 		}
 		db.Create(&p)
 
@@ -190,46 +277,138 @@ func GetEvents(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+type UpdateEventInput struct {
+	Name            *string `json:"name"`
+	RemainderPolicy *string `json:"remainder_policy"`
+	SimplifyDebts   *bool   `json:"simplify_debts"`
+	Currency        *string `json:"currency"`
+}
+
+func UpdateEvent(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var event models.Event
+		if err := db.First(&event, c.Param("id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+
+		var input UpdateEventInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if input.Name != nil {
+			event.Name = *input.Name
+		}
+		if input.RemainderPolicy != nil {
+			if !split.Policy(*input.RemainderPolicy).Valid() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Неизвестное правило распределения остатка"})
+				return
+			}
+			event.RemainderPolicy = *input.RemainderPolicy
+		}
+		if input.SimplifyDebts != nil {
+			event.SimplifyDebts = *input.SimplifyDebts
+		}
+		if input.Currency != nil {
+			if !money.Currency(*input.Currency).Valid() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Валюта должна быть кодом ISO 4217 с двумя знаками после запятой, например RUB"})
+				return
+			}
+			if *input.Currency != event.Currency {
+				// Суммы хранятся без валюты, поэтому смена валюты переписала бы
+				// смысл всех уже внесённых трат и платежей.
+				used, err := ledgerUsed(db, event.ID)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+					return
+				}
+				if used {
+					c.JSON(http.StatusConflict, gin.H{"error": "Валюту нельзя сменить: в событии уже есть траты или платежи"})
+					return
+				}
+			}
+			event.Currency = *input.Currency
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&event).Error; err != nil {
+				return err
+			}
+			return ledger.Recompute(tx, event.ID)
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
+			return
+		}
+		c.JSON(http.StatusOK, event)
+	}
+}
+
+// ledgerUsed сообщает, есть ли в событии траты или платежи.
+func ledgerUsed(db *gorm.DB, eventID uint) (bool, error) {
+	var n int64
+	if err := db.Model(&models.Expense{}).Where("event_id = ?", eventID).Count(&n).Error; err != nil || n > 0 {
+		return n > 0, err
+	}
+	err := db.Model(&models.Payment{}).Where("event_id = ?", eventID).Count(&n).Error
+	return n > 0, err
+}
+
 type AddParticipantRequest struct {
 	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role"`
 }
 
+// AddParticipant приглашает пользователя в событие по email. Участником он
+// становится, только приняв приглашение; если аккаунта с таким email ещё нет,
+// приглашение ждёт регистрации.
 func AddParticipant(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		eventID := c.Param("id")
+		eventID := parseUint(c.Param("id"))
 		var req AddParticipantRequest
 
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
 			return
 		}
+		email := normalizeEmail(req.Email)
 
-		// Найти пользователя по email
+		// Проверить, существует ли уже связь
 		var user models.User
-		if err := db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-			return
+		if err := db.Where("LOWER(email) = ?", email).First(&user).Error; err == nil {
+			var existing models.EventParticipant
+			if err := db.Where("event_id = ? AND user_id = ?", eventID, user.ID).First(&existing).Error; err == nil {
+				c.JSON(http.StatusConflict, gin.H{"error": "user already a participant"})
+				return
+			}
 		}
 
-		// Проверить, существует ли уже связь
-		var existing models.EventParticipant
-		if err := db.Where("event_id = ? AND user_id = ?", eventID, user.ID).First(&existing).Error; err == nil {
-			c.JSON(http.StatusConflict, gin.H{"error": "user already a participant"})
+		var pending int64
+		if err := db.Model(&models.EventInvite{}).
+			Where("event_id = ? AND email = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)",
+				eventID, email, models.InviteStatusPending, time.Now()).
+			Count(&pending).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check invites"})
 			return
 		}
-
-		// Добавить участника
-		participant := models.EventParticipant{
-			EventID: parseUint(eventID),
-			UserID:  user.ID,
+		if pending > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "user already invited"})
+			return
 		}
 
-		if err := db.Create(&participant).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add participant"})
+		invite, err := newInvite(eventID, c.GetUint("user_id"), CreateInviteInput{Email: &email, Role: req.Role})
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := db.Create(&invite).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to invite participant"})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"message": "participant added"})
+		c.JSON(http.StatusAccepted, gin.H{"message": "invitation sent", "invite": invite})
 	}
 }
 
@@ -238,6 +417,122 @@ func parseUint(s string) uint {
 	return uint(id)
 }
 
+func RemoveParticipant(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID := common.ParseUintParam(c.Param("id"))
+		userID := common.ParseUintParam(c.Param("user_id"))
+
+		var participant models.EventParticipant
+		if err := db.Where("event_id = ? AND user_id = ?", eventID, userID).First(&participant).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "participant not found"})
+			return
+		}
+		if participant.Role == models.RoleOwner {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "transfer ownership before removing the owner"})
+			return
+		}
+
+		var openDebts int64
+		if err := db.Model(&models.Debt{}).
+			Where("event_id = ? AND is_settled = false AND (from_user = ? OR to_user = ?)", eventID, userID, userID).
+			Count(&openDebts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check debts"})
+			return
+		}
+		if openDebts > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "participant has open debts in this event"})
+			return
+		}
+
+		if err := db.Delete(&participant).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove participant"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+type UpdateRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// UpdateParticipantRole меняет роль участника. Владельца так не назначить и не
+// понизить — для этого есть TransferOwnership.
+func UpdateParticipantRole(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID := common.ParseUintParam(c.Param("id"))
+		userID := common.ParseUintParam(c.Param("user_id"))
+
+		var req UpdateRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+		if !models.ValidRole(req.Role) || req.Role == models.RoleOwner {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role"})
+			return
+		}
+
+		var participant models.EventParticipant
+		if err := db.Where("event_id = ? AND user_id = ?", eventID, userID).First(&participant).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "participant not found"})
+			return
+		}
+		if participant.Role == models.RoleOwner {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "use ownership transfer to change the owner's role"})
+			return
+		}
+
+		participant.Role = req.Role
+		if err := db.Save(&participant).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update role"})
+			return
+		}
+		c.JSON(http.StatusOK, participant)
+	}
+}
+
+type TransferOwnershipRequest struct {
+	UserID uint `json:"user_id" binding:"required"`
+}
+
+// TransferOwnership передаёт владение событием другому участнику;
+// прежний владелец становится админом.
+func TransferOwnership(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		owner, _ := middleware.Participant(c)
+
+		var req TransferOwnershipRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		var next models.EventParticipant
+		if err := db.Where("event_id = ? AND user_id = ?", owner.EventID, req.UserID).First(&next).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "participant not found"})
+			return
+		}
+		if next.ID == owner.ID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "already the owner"})
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&owner).Update("role", models.RoleAdmin).Error; err != nil {
+				return err
+			}
+			return tx.Model(&next).Update("role", models.RoleOwner).Error
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to transfer ownership"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "ownership transferred", "owner": next})
+	}
+}
+
 func ListParticipants(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		eventID := c.Param("id")
@@ -248,164 +543,548 @@ func ListParticipants(db *gorm.DB) gin.HandlerFunc {
 }
 
 func AddExpense(c *gin.Context, db *gorm.DB) {
-	eventID := c.Param("id")
-	fmt.Print(eventID)
+	eventID := common.ParseUintParam(c.Param("id"))
 	var input CreateExpenseInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var totalShares float64
-	for _, share := range input.Shares {
-		totalShares += share.ShareAmount
-	}
-	if totalShares != input.Amount {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Сумма долей не совпадает с общей суммой"})
+	var event models.Event
+	if err := db.First(&event, eventID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Событие не найдено"})
 		return
 	}
 
 	expense := models.Expense{
-		EventID: common.ParseUintParam(eventID),
-		Title:   input.Title,
-		Amount:  input.Amount,
-		PaidBy:  input.PaidBy,
-		PaidAt:  time.Now(),
+		PaidAt:    time.Now(),
+		CreatedBy: c.GetUint("user_id"),
 	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return CreateExpense(tx, event, &expense, input)
+	})
 
+	if errors.Is(err, split.ErrInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании траты"})
+		return
+	}
+
+	// В ответе — сохранённая трата с разбивкой долей по участникам и надбавками.
+	if err := loadExpenseDetails(db, &expense); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при загрузке траты"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Трата добавлена", "expense_id": expense.ID, "expense": expense})
+}
+
+// loadExpenseDetails перечитывает трату вместе с долями, позициями и надбавками.
+func loadExpenseDetails(db *gorm.DB, expense *models.Expense) error {
+	return db.Preload("Shares").Preload("Items.Assignments").Preload("Adjustments").
+		First(expense, expense.ID).Error
+}
+
+// CreateExpense создаёт трату в событии тем же путём, что и POST /events/:id/expenses:
+// считает доли, сохраняет трату и пересчитывает долги. Вызывать внутри транзакции.
+func CreateExpense(tx *gorm.DB, event models.Event, expense *models.Expense, input CreateExpenseInput) error {
+	expense.EventID = event.ID
+	rem, err := newRemainder(tx, event, input.PaidBy)
+	if err != nil {
+		return err
+	}
+	if err := saveExpense(tx, expense, input, rem); err != nil {
+		return err
+	}
+	return ledger.Recompute(tx, event.ID)
+}
+
+// saveExpense применяет input к трате: считает доли, сохраняет трату и
+// заменяет её доли. Долги не трогает — после неё нужен ledger.Recompute.
+func saveExpense(tx *gorm.DB, expense *models.Expense, input CreateExpenseInput, rem split.Remainder) error {
+	items, itemsTotal, err := buildItems(input.Items)
+	if err != nil {
+		return err
+	}
+	if len(items) > 0 && input.SplitMode != "" && input.SplitMode != split.ModeItemized {
+		return fmt.Errorf("%w: позиции чека задаются только при split_mode %s", split.ErrInvalid, split.ModeItemized)
+	}
+	if len(input.Adjustments) > 0 {
+		if err := resolveTotals(&input, itemsTotal); err != nil {
+			return err
+		}
+	} else {
+		input.Subtotal = 0
+		if len(items) > 0 && input.Amount == 0 {
+			input.Amount = itemsTotal
+		}
+	}
+	if input.Amount <= 0 {
+		return fmt.Errorf("%w: сумма траты должна быть положительной", split.ErrInvalid)
+	}
+
+	mode, shares, err := buildShares(tx, expense.EventID, input, rem)
+	if err != nil {
+		return err
+	}
+	expense.Title = input.Title
+	expense.Amount = input.Amount
+	expense.Subtotal = input.Subtotal
+	expense.PaidBy = input.PaidBy
+	expense.SplitMode = string(mode)
+	expense.RemainderPolicy = string(rem.Policy)
+	expense.RemainderSeed = rem.Seed
 	if input.PaidAt != nil {
 		expense.PaidAt = *input.PaidAt
 	}
 
-	if err := db.Create(&expense).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании траты"})
-		return
+	if err := tx.Omit(clause.Associations).Save(expense).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("expense_id = ?", expense.ID).Delete(&models.ExpenseShare{}).Error; err != nil {
+		return err
 	}
 
-	for _, s := range input.Shares {
-		share := models.ExpenseShare{
-			ExpenseID:   expense.ID,
-			UserID:      s.UserID,
-			ShareAmount: s.ShareAmount,
+	for i := range shares {
+		shares[i].ExpenseID = expense.ID
+	}
+	if len(shares) > 0 {
+		if err := tx.Create(&shares).Error; err != nil {
+			return err
 		}
-		db.Create(&share)
 	}
+	expense.Shares = shares
+	if err := replaceAdjustments(tx, expense, input.Adjustments, input.Subtotal); err != nil {
+		return err
+	}
+	return replaceItems(tx, expense, items)
+}
 
-	for _, s := range input.Shares {
-		if s.UserID == input.PaidBy || s.ShareAmount == 0 {
-			continue
+// buildShares считает доли траты по выбранному способу деления.
+// Параметры деления (проценты, веса) сохраняются в долях, чтобы при
+// редактировании траты их можно было применить заново. Если у траты есть
+// надбавки, доли считаются от подытога, а надбавки раскладываются поверх.
+func buildShares(db *gorm.DB, eventID uint, input CreateExpenseInput, rem split.Remainder) (split.Mode, []models.ExpenseShare, error) {
+	mode := input.SplitMode
+	if mode == "" {
+		switch {
+		case len(input.Items) > 0:
+			mode = split.ModeItemized
+		case len(input.Shares) > 0:
+			mode = split.ModeExact
+		default:
+			mode = split.ModeEqual
 		}
+	}
 
-		fromID := s.UserID
-		toID := input.PaidBy
-		amount := s.ShareAmount
+	var participants []uint
+	if err := db.Model(&models.EventParticipant{}).
+		Where("event_id = ?", eventID).
+		Pluck("user_id", &participants).Error; err != nil {
+		return "", nil, err
+	}
 
-		var reverseDebt models.Debt
-		err := db.Where("event_id = ? AND from_user = ? AND to_user = ? AND is_settled = false",
-			expense.EventID, toID, fromID).First(&reverseDebt).Error
-
-		if err == nil {
-			if reverseDebt.Amount > amount {
-				reverseDebt.Amount -= amount
-				db.Save(&reverseDebt)
-			} else if reverseDebt.Amount < amount {
-				db.Delete(&reverseDebt)
-
-				newAmount := amount - reverseDebt.Amount
-				newDebt := models.Debt{
-					EventID:  expense.EventID,
-					FromUser: fromID,
-					ToUser:   toID,
-					Amount:   newAmount,
-				}
-				db.Create(&newDebt)
-			} else {
-				db.Delete(&reverseDebt)
-			}
+	if !containsUser(participants, input.PaidBy) {
+		return "", nil, fmt.Errorf("%w: плательщик не участвует в событии", split.ErrInvalid)
+	}
 
-		} else if errors.Is(err, gorm.ErrRecordNotFound) {
-			// Нет обратного — ищем прямой
-			var existingDebt models.Debt
-			err := db.Where("event_id = ? AND from_user = ? AND to_user = ? AND is_settled = false",
-				expense.EventID, fromID, toID).First(&existingDebt).Error
-
-			if err == nil {
-				existingDebt.Amount += amount
-				db.Save(&existingDebt)
-			} else if errors.Is(err, gorm.ErrRecordNotFound) {
-				newDebt := models.Debt{
-					EventID:  expense.EventID,
-					FromUser: fromID,
-					ToUser:   toID,
-					Amount:   amount,
-				}
-				db.Create(&newDebt)
-			} else {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при поиске долга"})
-				return
-			}
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при поиске обратного долга"})
-			return
+	parts := make([]split.Part, len(input.Shares))
+	params := make(map[uint]ShareInput, len(input.Shares))
+	for i, s := range input.Shares {
+		parts[i] = split.Part{
+			UserID:  s.UserID,
+			Amount:  s.ShareAmount,
+			Percent: s.Percent,
+			Weight:  s.Weight,
 		}
+		params[s.UserID] = s
+	}
+
+	base := input.Amount
+	if len(input.Adjustments) > 0 {
+		base = input.Subtotal
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Трата добавлена", "expense_id": expense.ID})
+	var computed []split.Share
+	var err error
+	if mode == split.ModeItemized {
+		var items []models.ExpenseItem
+		if items, _, err = buildItems(input.Items); err != nil {
+			return "", nil, err
+		}
+		computed, err = itemizedShares(items, base, participants, rem)
+	} else {
+		computed, err = split.Compute(split.Request{
+			Mode:         mode,
+			Total:        base,
+			Participants: participants,
+			Parts:        parts,
+			Remainder:    rem,
+		})
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	shares := make([]models.ExpenseShare, len(computed))
+	for i, s := range computed {
+		shares[i] = models.ExpenseShare{
+			UserID:      s.UserID,
+			ShareAmount: s.Amount,
+		}
+		switch mode {
+		case split.ModePercentage:
+			shares[i].Percent = params[s.UserID].Percent
+		case split.ModeWeights:
+			shares[i].Weight = params[s.UserID].Weight
+		}
+	}
+	if len(input.Adjustments) > 0 {
+		if err := applyAdjustments(shares, input.Adjustments, rem); err != nil {
+			return "", nil, err
+		}
+	}
+	return mode, shares, nil
+}
+
+// newRemainder фиксирует правило остатка для новой траты. Seed сохраняется в трате:
+// для round_robin это порядковый номер траты в событии (смещение круга),
+// для random — зерно генератора, поэтому пересчёт долей всегда даёт тот же результат.
+func newRemainder(db *gorm.DB, event models.Event, payer uint) (split.Remainder, error) {
+	rem := split.Remainder{
+		Policy: split.Policy(event.RemainderPolicy),
+		Payer:  payer,
+	}
+	if rem.Policy == "" {
+		rem.Policy = split.DefaultPolicy
+	}
+
+	switch rem.Policy {
+	case split.PolicyRoundRobin:
+		var count int64
+		if err := db.Model(&models.Expense{}).Where("event_id = ?", event.ID).Count(&count).Error; err != nil {
+			return rem, err
+		}
+		rem.Seed = count
+	case split.PolicyRandom:
+		rem.Seed = rand.Int63()
+	}
+	return rem, nil
+}
+
+func containsUser(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
 }
 
 func ListExpenses(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		eventID := c.Param("id")
 		var expenses []models.Expense
-		db.Where("event_id = ?", eventID).Find(&expenses)
+		db.Preload("Shares").Preload("Items.Assignments").Preload("Adjustments").
+			Where("event_id = ?", eventID).Order("paid_at, id").Find(&expenses)
 		c.JSON(http.StatusOK, expenses)
 	}
 }
 
+// UpdateExpense принимает тот же input, что и AddExpense. Пустые title, amount
+// и paid_by оставляют текущие значения; если не заданы ни split_mode, ни shares,
+// заново применяется сохранённый способ деления. Без adjustments сохраняются
+// текущие надбавки, пустой список их убирает.
 func UpdateExpense(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id := c.Param("id")
 		var expense models.Expense
-		if err := db.First(&expense, id).Error; err != nil {
+		if err := db.Preload("Shares").Preload("Adjustments").First(&expense, c.Param("id")).Error; err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
 			return
 		}
-		if err := c.ShouldBindJSON(&expense); err != nil {
+		if !canEditExpense(c, expense) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Участник может редактировать только свои траты"})
+			return
+		}
+
+		var input CreateExpenseInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if input.Title == "" {
+			input.Title = expense.Title
+		}
+		if input.Adjustments == nil {
+			input.Adjustments = adjustmentInputs(expense.Adjustments)
+		}
+		if input.Amount == 0 && input.Subtotal == 0 && len(input.Items) == 0 {
+			// Прежний подытог — это сумма без надбавок (у траты без надбавок — вся сумма).
+			subtotal := expense.Subtotal
+			if subtotal == 0 {
+				subtotal = expense.Amount
+			}
+			if len(input.Adjustments) > 0 {
+				input.Subtotal = subtotal
+			} else {
+				input.Amount = subtotal
+			}
+		} else if input.Subtotal == 0 && len(input.Items) == 0 && len(input.Adjustments) > 0 && expense.Subtotal > 0 {
+			// Пришла только сумма: подытог остаётся прежним, а amount сверяется с ним и надбавками.
+			input.Subtotal = expense.Subtotal
+		}
+		if input.PaidBy == 0 {
+			input.PaidBy = expense.PaidBy
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if input.SplitMode == "" && len(input.Shares) == 0 && len(input.Items) == 0 {
+				if err := storedSplit(tx, expense, &input); err != nil {
+					return err
+				}
+			}
+
+			rem := split.Remainder{
+				Policy: split.Policy(expense.RemainderPolicy),
+				Payer:  input.PaidBy,
+				Seed:   expense.RemainderSeed,
+			}
+			if err := saveExpense(tx, &expense, input, rem); err != nil {
+				return err
+			}
+			return ledger.Recompute(tx, expense.EventID)
+		})
+
+		if errors.Is(err, split.ErrInvalid) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при обновлении траты"})
+			return
+		}
+		if err := loadExpenseDetails(db, &expense); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при загрузке траты"})
+			return
 		}
-		db.Save(&expense)
 		c.JSON(http.StatusOK, expense)
 	}
 }
 
-func DeleteExpense(db *gorm.DB) gin.HandlerFunc {
+// canEditExpense: админы и владелец правят любые траты события, участники —
+// только созданные или оплаченные ими, наблюдатели — никакие.
+func canEditExpense(c *gin.Context, expense models.Expense) bool {
+	return canEditOwn(c, expense.CreatedBy, expense.PaidBy)
+}
+
+func canEditOwn(c *gin.Context, owners ...uint) bool {
+	participant, ok := middleware.Participant(c)
+	if !ok {
+		return false
+	}
+	if participant.HasRole(models.RoleAdmin) {
+		return true
+	}
+	if !participant.HasRole(models.RoleMember) {
+		return false
+	}
+	return containsUser(owners, participant.UserID)
+}
+
+// storedSplit восстанавливает input деления из сохранённых долей и позиций траты.
+func storedSplit(tx *gorm.DB, expense models.Expense, input *CreateExpenseInput) error {
+	input.SplitMode = split.Mode(expense.SplitMode)
+
+	switch input.SplitMode {
+	case split.ModeItemized:
+		var items []models.ExpenseItem
+		if err := tx.Preload("Assignments").Where("expense_id = ?", expense.ID).
+			Order("id").Find(&items).Error; err != nil {
+			return err
+		}
+		input.Items = itemInputs(items)
+		return nil
+	case split.ModeExcept:
+		// Исключённые участники долей не имеют — восстанавливаем их по составу события.
+		var participants []uint
+		if err := tx.Model(&models.EventParticipant{}).
+			Where("event_id = ?", expense.EventID).
+			Pluck("user_id", &participants).Error; err != nil {
+			return err
+		}
+		included := make(map[uint]bool, len(expense.Shares))
+		for _, s := range expense.Shares {
+			included[s.UserID] = true
+		}
+		for _, id := range participants {
+			if !included[id] {
+				input.Shares = append(input.Shares, ShareInput{UserID: id})
+			}
+		}
+		return nil
+	}
+
+	for _, s := range expense.Shares {
+		amount := s.ShareAmount
+		if expense.Subtotal != 0 {
+			// Точные суммы вводились без надбавок.
+			amount = s.Subtotal
+		}
+		input.Shares = append(input.Shares, ShareInput{
+			UserID:      s.UserID,
+			ShareAmount: amount,
+			Percent:     s.Percent,
+			Weight:      s.Weight,
+		})
+	}
+	return nil
+}
+
+// DeleteExpense удаляет трату вместе с долями и вложениями и пересчитывает долги события.
+// Если между плательщиком и участниками траты уже были платежи, удаление
+// меняет уже частично погашенные долги, поэтому требует ?confirm=true.
+func DeleteExpense(db *gorm.DB, store storage.Storage) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		id := c.Param("id")
-		db.Delete(&models.Expense{}, id)
-		db.Where("expense_id = ?", id).Delete(&models.ExpenseShare{})
-		c.Status(http.StatusNoContent)
+		confirmed := c.Query("confirm") == "true"
+
+		var expense models.Expense
+		var payments []models.Payment
+		var attachments []models.Attachment
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Preload("Shares").First(&expense, c.Param("id")).Error; err != nil {
+				return err
+			}
+			if !canEditExpense(c, expense) {
+				return errForbidden
+			}
+
+			var debtors []uint
+			for _, s := range expense.Shares {
+				if s.UserID != expense.PaidBy && s.ShareAmount > 0 {
+					debtors = append(debtors, s.UserID)
+				}
+			}
+			if len(debtors) > 0 {
+				// Учитываются только платежи, которые действительно погасили долг:
+				// подтверждённые, не сторно и не отменённые.
+				if err := tx.Where("event_id = ? AND ((from_user IN ? AND to_user = ?) OR (from_user = ? AND to_user IN ?))",
+					expense.EventID, debtors, expense.PaidBy, expense.PaidBy, debtors).
+					Where("status = ? AND reversal_of IS NULL", models.PaymentConfirmed).
+					Where("id NOT IN (?)", tx.Model(&models.Payment{}).Select("reversal_of").Where("reversal_of IS NOT NULL")).
+					Order("id").Find(&payments).Error; err != nil {
+					return err
+				}
+			}
+			if len(payments) > 0 && !confirmed {
+				return errNeedsConfirmation
+			}
+
+			if err := tx.Where("expense_id = ?", expense.ID).Delete(&models.ExpenseShare{}).Error; err != nil {
+				return err
+			}
+			if err := replaceItems(tx, &expense, nil); err != nil {
+				return err
+			}
+			if err := replaceAdjustments(tx, &expense, nil, 0); err != nil {
+				return err
+			}
+			if err := tx.Where("expense_id = ?", expense.ID).Find(&attachments).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("expense_id = ?", expense.ID).Delete(&models.Attachment{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&expense).Error; err != nil {
+				return err
+			}
+			return ledger.Recompute(tx, expense.EventID)
+		})
+
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Expense not found"})
+		case errors.Is(err, errForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Участник может удалять только свои траты"})
+		case errors.Is(err, errNeedsConfirmation):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":    "Долги по этой трате уже частично погашены платежами; повторите запрос с ?confirm=true",
+				"payments": payments,
+			})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении траты"})
+		default:
+			deleteAttachmentFiles(c.Request.Context(), store, attachments...)
+			c.Status(http.StatusNoContent)
+		}
 	}
 }
 
+// SummaryEntry — итоги участника: сколько он заплатил за траты, его доля
+// в них, сколько перевёл и получил платежами и чистый баланс
+// (paid - share + sent - received): положительный — ему должны.
+type SummaryEntry struct {
+	UserID   uint        `json:"user_id"`
+	Name     string      `json:"name"`
+	Paid     money.Money `json:"paid"`
+	Share    money.Money `json:"share"`
+	Sent     money.Money `json:"sent"`
+	Received money.Money `json:"received"`
+	Net      money.Money `json:"net"`
+}
+
+// GetEventSummary возвращает сумму трат события и итоги по каждому участнику,
+// включая покинувших событие, если у них остались траты или платежи.
+// Балансы всех участников в сумме дают ноль; если это не так, данные события
+// повреждены, и вместо неверной сводки возвращается ошибка.
 func GetEventSummary(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		eventID := c.Param("id")
-		var total float64
-		db.Model(&models.Expense{}).Where("event_id = ?", eventID).Select("SUM(amount)").Scan(&total)
+		eventID := common.ParseUintParam(c.Param("id"))
 
-		type Share struct {
-			UserID uint    `json:"user_id"`
-			Amount float64 `json:"amount"`
+		var total money.Money
+		if err := db.Model(&models.Expense{}).Where("event_id = ?", eventID).
+			Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при подсчёте итогов"})
+			return
+		}
+		positions, err := ledger.Positions(db, eventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при подсчёте итогов"})
+			return
 		}
 
-		var shares []Share
-		db.Table("expense_shares").Select("user_id, SUM(share_amount) as amount").
-			Joins("JOIN expenses ON expense_shares.expense_id = expenses.id").
-			Where("expenses.event_id = ?", eventID).
-			Group("user_id").Scan(&shares)
+		referenced := make([]uint, 0, len(positions))
+		for id := range positions {
+			referenced = append(referenced, id)
+		}
+		ids, names, err := participantNames(db, eventID, referenced)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при подсчёте итогов"})
+			return
+		}
+
+		var sum money.Money
+		entries := make([]SummaryEntry, 0, len(ids))
+		for _, id := range ids {
+			p := positions[id]
+			entries = append(entries, SummaryEntry{
+				UserID:   id,
+				Name:     names[id],
+				Paid:     p.Paid,
+				Share:    p.Share,
+				Sent:     p.Sent,
+				Received: p.Received,
+				Net:      p.Net(),
+			})
+			sum += p.Net()
+		}
+		if sum != 0 {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": fmt.Sprintf("Балансы события не сходятся на %s: доли трат не совпадают с их суммами", sum),
+			})
+			return
+		}
 
-		c.JSON(http.StatusOK, gin.H{"total": total, "shares": shares})
+		c.JSON(http.StatusOK, gin.H{"total": total, "participants": entries})
 	}
 }
 
@@ -418,6 +1097,107 @@ func GetDebts(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
+type BalanceEntry struct {
+	UserID  uint        `json:"user_id"`
+	Balance money.Money `json:"balance"`
+}
+
+func settlePlan(db *gorm.DB, eventID uint) ([]BalanceEntry, []ledger.Transfer, error) {
+	balances, err := ledger.Balances(db, eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := make([]BalanceEntry, 0, len(balances))
+	for id, b := range balances {
+		entries = append(entries, BalanceEntry{UserID: id, Balance: b})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].UserID < entries[j].UserID })
+
+	return entries, ledger.Simplify(balances), nil
+}
+
+func GetSettlePlan(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID := common.ParseUintParam(c.Param("id"))
+		balances, transfers, err := settlePlan(db, eventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при расчёте балансов"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"balances": balances, "transfers": transfers})
+	}
+}
+
+// ApplySettlePlan включает для события упрощение долгов: с этого момента
+// открытые долги события строятся по упрощённому плану (не более n-1 переводов).
+func ApplySettlePlan(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID := common.ParseUintParam(c.Param("id"))
+
+		var debts []models.Debt
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Event{}).Where("id = ?", eventID).
+				Update("simplify_debts", true).Error; err != nil {
+				return err
+			}
+			if err := ledger.Recompute(tx, eventID); err != nil {
+				return err
+			}
+			return tx.Where("event_id = ? AND is_settled = false", eventID).Find(&debts).Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Событие не найдено"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при применении плана"})
+			return
+		}
+
+		c.JSON(http.StatusOK, debts)
+	}
+}
+
+// RecomputeDebts пересобирает долги события с нуля по тратам и платежам.
+// Доступен админам события и, по /admin/events/:id, администраторам сервиса.
+func RecomputeDebts(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID := common.ParseUintParam(c.Param("id"))
+
+		var debts []models.Debt
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := ledger.Recompute(tx, eventID); err != nil {
+				return err
+			}
+			return tx.Where("event_id = ? AND is_settled = false", eventID).Find(&debts).Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Событие не найдено"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при пересчёте долгов"})
+			return
+		}
+
+		c.JSON(http.StatusOK, debts)
+	}
+}
+
+type AddPaymentInput struct {
+	FromUser uint        `json:"from_user"`
+	ToUser   uint        `json:"to_user"`
+	Amount   money.Money `json:"amount"`
+	PaidAt   *time.Time  `json:"paid_at"`
+}
+
+// AddPayment записывает платёж. Платёж, о котором сообщает плательщик, ждёт
+// подтверждения получателя и до него долги не уменьшает; если платёж записывает
+// сам получатель (указав from_user), он подтверждён сразу. Гость войти не может,
+// поэтому платёж гостю подтверждает админ события; записанный им, он тоже
+// подтверждён сразу (без from_user плательщиком считается сам админ).
+// Платёж больше долга принимается: переплата становится встречным долгом
+// получателя, который погасят следующие траты плательщика. В ответе applied
+// объясняет, как разошлась сумма, и показывает долги пары после платежа.
 func AddPayment(c *gin.Context, db *gorm.DB) {
 	userID, err := GetUserID(c)
 	if err != nil {
@@ -425,59 +1205,87 @@ func AddPayment(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
-	var input models.Payment
+	var input AddPaymentInput
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if input.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Сумма платежа должна быть положительной"})
+		return
+	}
 
-	input.FromUser = userID
+	payment := models.Payment{
+		EventID:  common.ParseUintParam(c.Param("id")),
+		FromUser: userID,
+		ToUser:   input.ToUser,
+		Amount:   input.Amount,
+		PaidAt:   time.Now(),
+		Status:   models.PaymentPending,
+	}
+	confirms, err := confirmsFor(db, payment.EventID, input.ToUser, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при добавлении платежа"})
+		return
+	}
+	if confirms {
+		if input.FromUser != 0 || input.ToUser == userID {
+			payment.FromUser = input.FromUser
+		}
+		payment.Status = models.PaymentConfirmed
+	} else if input.FromUser != 0 && input.FromUser != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Чужой платёж может записать только его получатель"})
+		return
+	}
+	if input.PaidAt != nil {
+		payment.PaidAt = *input.PaidAt
+	}
+	if payment.FromUser == 0 || payment.FromUser == payment.ToUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нужны разные отправитель и получатель"})
+		return
+	}
 
+	var applied PaymentApplication
 	err = db.Transaction(func(tx *gorm.DB) error {
-		var totalDebt float64
-		if err := tx.Model(&models.Debt{}).
-			Where("event_id = ? AND from_user = ? AND to_user = ? AND is_settled = false",
-				input.EventID, input.FromUser, input.ToUser).
-			Select("COALESCE(SUM(amount), 0)").Scan(&totalDebt).Error; err != nil {
+		counterpart := payment.ToUser
+		if counterpart == userID {
+			counterpart = payment.FromUser
+		}
+		var count int64
+		if err := tx.Model(&models.EventParticipant{}).
+			Where("event_id = ? AND user_id = ?", payment.EventID, counterpart).
+			Count(&count).Error; err != nil {
 			return err
 		}
-
-		if input.Amount > totalDebt {
-			return fmt.Errorf("сумма оплаты превышает текущий долг (%.2f)", totalDebt)
+		if count == 0 {
+			return fmt.Errorf("пользователь %d не участвует в событии", counterpart)
 		}
 
-		if err := tx.Create(&input).Error; err != nil {
+		var outstanding money.Money
+		if err := tx.Model(&models.Debt{}).
+			Where("event_id = ? AND from_user = ? AND to_user = ? AND is_settled = false",
+				payment.EventID, payment.FromUser, payment.ToUser).
+			Select("COALESCE(SUM(amount), 0)").Scan(&outstanding).Error; err != nil {
 			return err
 		}
+		applied = applyPayment(payment.Amount, outstanding)
+		applied.Projected = payment.Status != models.PaymentConfirmed
 
-		remaining := input.Amount
-		var debts []models.Debt
-		if err := tx.Where("event_id = ? AND from_user = ? AND to_user = ? AND is_settled = false",
-			input.EventID, input.FromUser, input.ToUser).
-			Order("id").Find(&debts).Error; err != nil {
+		if err := tx.Create(&payment).Error; err != nil {
 			return err
 		}
-
-		for _, debt := range debts {
-			if remaining <= 0 {
-				break
-			}
-
-			if remaining >= debt.Amount {
-				remaining -= debt.Amount
-				debt.Amount = 0
-				debt.IsSettled = true
-			} else {
-				debt.Amount -= remaining
-				remaining = 0
-			}
-
-			if err := tx.Save(&debt).Error; err != nil {
+		if err := recordPaymentStatus(tx, &payment, userID, ""); err != nil {
+			return err
+		}
+		if payment.Status == models.PaymentConfirmed {
+			if err := ledger.Recompute(tx, payment.EventID); err != nil {
 				return err
 			}
 		}
-
-		return nil
+		return tx.Where("event_id = ? AND is_settled = false", payment.EventID).
+			Where("(from_user = ? AND to_user = ?) OR (from_user = ? AND to_user = ?)",
+				payment.FromUser, payment.ToUser, payment.ToUser, payment.FromUser).
+			Find(&applied.Debts).Error
 	})
 
 	if err != nil {
@@ -485,14 +1293,26 @@ func AddPayment(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Платёж успешно добавлен и долги обновлены"})
+	message := "Платёж успешно добавлен и долги обновлены"
+	switch {
+	case applied.Projected && applied.Credit > 0:
+		message = fmt.Sprintf("Платёж добавлен и ждёт подтверждения получателя; после подтверждения переплата %s будет засчитана как долг получателя", applied.Credit)
+	case applied.Projected:
+		message = "Платёж добавлен и ждёт подтверждения получателя; долги изменятся после подтверждения"
+	case applied.Credit > 0:
+		message += fmt.Sprintf("; переплата %s засчитана как долг получателя", applied.Credit)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "payment": payment, "applied": applied})
 }
 
+// ListPayments возвращает платежи события вместе с историей их статусов.
 func ListPayments(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		eventID := c.Param("id")
 		var payments []models.Payment
-		db.Where("event_id = ?", eventID).Find(&payments)
+		db.Preload("History", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at, id")
+		}).Where("event_id = ?", eventID).Order("paid_at, id").Find(&payments)
 		c.JSON(http.StatusOK, payments)
 	}
 }
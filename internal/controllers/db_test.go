@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"split-the-bill/internal/config"
+	"split-the-bill/internal/middleware"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newTestDB открывает пустую SQLite-базу во временном каталоге со схемой приложения.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)&_pragma=foreign_keys(0)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(config.Models...); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// testRouter — gin без JWT: пользователя задаёт заголовок X-User-ID.
+// Маршруты события регистрируются в группе event с middleware.EventMember.
+func testRouter(db *gorm.DB) (*gin.Engine, *gin.RouterGroup) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		id, _ := strconv.ParseUint(c.GetHeader("X-User-ID"), 10, 64)
+		c.Set("user_id", uint(id))
+	})
+	return r, r.Group("/events/:id", middleware.EventMember(db))
+}
+
+func request(t *testing.T, h http.Handler, method, path string, userID uint, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("X-User-ID", fmt.Sprint(userID))
+	if body != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func createUser(t *testing.T, db *gorm.DB, name string) models.User {
+	t.Helper()
+	user := models.User{Name: name}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// createEvent создаёт событие в рублях; первый участник — владелец.
+func createEvent(t *testing.T, db *gorm.DB, owner uint, roles map[uint]string) models.Event {
+	t.Helper()
+	event := models.Event{Name: "Поездка", CreatedBy: owner, Currency: string(money.DefaultCurrency)}
+	if err := db.Create(&event).Error; err != nil {
+		t.Fatal(err)
+	}
+	participants := []models.EventParticipant{{EventID: event.ID, UserID: owner, Role: models.RoleOwner}}
+	for id, role := range roles {
+		participants = append(participants, models.EventParticipant{EventID: event.ID, UserID: id, Role: role})
+	}
+	if err := db.Create(&participants).Error; err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+// openDebts возвращает открытые долги события как пары from -> to.
+func openDebts(t *testing.T, db *gorm.DB, eventID uint) map[[2]uint]money.Money {
+	t.Helper()
+	var debts []models.Debt
+	if err := db.Where("event_id = ? AND is_settled = false", eventID).Find(&debts).Error; err != nil {
+		t.Fatal(err)
+	}
+	open := make(map[[2]uint]money.Money, len(debts))
+	for _, d := range debts {
+		open[[2]uint{d.FromUser, d.ToUser}] = d.Amount
+	}
+	return open
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"sort"
+	"split-the-bill/internal/common"
+	"split-the-bill/internal/export"
+	"split-the-bill/internal/ledger"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+)
+
+// ExportEvent выгружает траты, платежи и итоговые балансы события.
+// ?format=xlsx — одна книга с тремя листами; ?format=csv (по умолчанию) —
+// ZIP с тремя CSV, а с ?sheet=expenses|payments|balances — один CSV.
+// Лист expenses совпадает с форматом импорта, поэтому его можно загрузить
+// обратно через POST /events/:id/import.
+func ExportEvent(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID := common.ParseUintParam(c.Param("id"))
+		format := c.DefaultQuery("format", "csv")
+		if format != "csv" && format != "xlsx" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format должен быть csv или xlsx"})
+			return
+		}
+
+		var event models.Event
+		if err := db.First(&event, eventID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Событие не найдено"})
+			return
+		}
+		currency := money.Currency(event.Currency)
+		if currency == "" {
+			currency = money.DefaultCurrency
+		}
+
+		sheets, err := exportSheets(db, eventID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при выгрузке события"})
+			return
+		}
+
+		var buf bytes.Buffer
+		var contentType, fileName string
+		switch sheet := c.Query("sheet"); {
+		case format == "xlsx":
+			err = export.WriteXLSX(&buf, sheets, currency)
+			contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+			fileName = fmt.Sprintf("event-%d.xlsx", eventID)
+		case sheet != "":
+			i := 0
+			for i < len(sheets) && sheets[i].Name != sheet {
+				i++
+			}
+			if i == len(sheets) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "sheet должен быть expenses, payments или balances"})
+				return
+			}
+			err = export.WriteCSV(&buf, sheets[i], currency)
+			contentType = "text/csv; charset=utf-8"
+			fileName = fmt.Sprintf("event-%d-%s.csv", eventID, sheet)
+		default:
+			err = export.WriteZip(&buf, sheets, currency)
+			contentType = "application/zip"
+			fileName = fmt.Sprintf("event-%d.zip", eventID)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при выгрузке события"})
+			return
+		}
+
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+		c.Data(http.StatusOK, contentType, buf.Bytes())
+	}
+}
+
+// exportSheets собирает листы expenses, payments и balances.
+func exportSheets(db *gorm.DB, eventID uint) ([]export.Sheet, error) {
+	var expenses []models.Expense
+	if err := db.Preload("Shares").Where("event_id = ?", eventID).
+		Order("paid_at, id").Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	var payments []models.Payment
+	if err := db.Where("event_id = ? AND status = ?", eventID, models.PaymentConfirmed).
+		Order("paid_at, id").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	positions, err := ledger.Positions(db, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	var referenced []uint
+	for id := range positions {
+		referenced = append(referenced, id)
+	}
+	for _, e := range expenses {
+		for _, s := range e.Shares {
+			referenced = append(referenced, s.UserID)
+		}
+	}
+	ids, names, err := participantNames(db, eventID, referenced)
+	if err != nil {
+		return nil, err
+	}
+
+	expenseSheet := export.Sheet{Name: "expenses", Header: []string{"date", "title", "amount", "paid_by"}}
+	column := make(map[uint]int, len(ids))
+	for i, id := range ids {
+		expenseSheet.Header = append(expenseSheet.Header, names[id])
+		column[id] = 4 + i
+	}
+	for _, e := range expenses {
+		row := make([]export.Cell, len(expenseSheet.Header))
+		row[0] = export.Date(e.PaidAt)
+		row[1] = export.Text(e.Title)
+		row[2] = export.Amount(e.Amount)
+		row[3] = export.Text(names[e.PaidBy])
+		for _, s := range e.Shares {
+			row[column[s.UserID]] = export.Amount(s.ShareAmount)
+		}
+		expenseSheet.Rows = append(expenseSheet.Rows, row)
+	}
+
+	paymentSheet := export.Sheet{Name: "payments", Header: []string{"date", "from", "to", "amount"}}
+	for _, p := range payments {
+		paymentSheet.Rows = append(paymentSheet.Rows, []export.Cell{
+			export.Date(p.PaidAt),
+			export.Text(names[p.FromUser]),
+			export.Text(names[p.ToUser]),
+			export.Amount(p.Amount),
+		})
+	}
+
+	balanceSheet := export.Sheet{Name: "balances", Header: []string{"name", "paid", "share", "sent", "received", "net"}}
+	for _, id := range ids {
+		p, ok := positions[id]
+		if !ok {
+			continue
+		}
+		balanceSheet.Rows = append(balanceSheet.Rows, []export.Cell{
+			export.Text(names[id]),
+			export.Amount(p.Paid),
+			export.Amount(p.Share),
+			export.Amount(p.Sent),
+			export.Amount(p.Received),
+			export.Amount(p.Net()),
+		})
+	}
+
+	return []export.Sheet{expenseSheet, paymentSheet, balanceSheet}, nil
+}
+
+// participantNames возвращает участников события в порядке вступления, а за
+// ними — остальных пользователей из extra (например, уже покинувших событие),
+// и их отображаемые имена. Совпадающие имена дополняются id, чтобы столбцы
+// таблиц не путались.
+func participantNames(db *gorm.DB, eventID uint, extra []uint) ([]uint, map[uint]string, error) {
+	var ids []uint
+	if err := db.Model(&models.EventParticipant{}).Where("event_id = ?", eventID).
+		Order("id").Pluck("user_id", &ids).Error; err != nil {
+		return nil, nil, err
+	}
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		seen[id] = true
+	}
+	sort.Slice(extra, func(i, j int) bool { return extra[i] < extra[j] })
+	for _, id := range extra {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	var users []models.User
+	if err := db.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, nil, err
+	}
+	names := make(map[uint]string, len(users))
+	count := make(map[string]int, len(users))
+	for _, u := range users {
+		name := displayName(u)
+		names[u.ID] = name
+		count[name]++
+	}
+	for id, name := range names {
+		if count[name] > 1 {
+			names[id] = fmt.Sprintf("%s (%d)", name, id)
+		}
+	}
+	return ids, names, nil
+}
+
+// displayName — имя пользователя, а если оно не задано, email или номер.
+func displayName(u models.User) string {
+	if u.Name != "" {
+		return u.Name
+	}
+	if u.Email != nil && *u.Email != "" {
+		return *u.Email
+	}
+	return fmt.Sprintf("Участник %d", u.ID)
+}
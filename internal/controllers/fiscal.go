@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"split-the-bill/internal/common"
+	"split-the-bill/internal/fiscal"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/split"
+	"strings"
+)
+
+// FiscalReceiptInput — строка из QR-кода чека ФНС и, по желанию, параметры
+// траты как у AddExpense. Сумма и время оплаты всегда берутся из чека.
+type FiscalReceiptInput struct {
+	QR string `json:"qr"`
+	CreateExpenseInput
+}
+
+// AddFiscalReceipt создаёт трату по QR-коду чека. Принимает JSON с полем qr
+// или multipart-форму с фотографией QR-кода в поле image (остальные
+// параметры — JSON в поле expense). С ?preview=true трата не создаётся,
+// а возвращаются реквизиты чека и заполненный input для AddExpense.
+// Один и тот же чек нельзя добавить в событие дважды.
+func AddFiscalReceipt(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		input, err := bindFiscalReceipt(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		receipt, err := fiscal.Parse(input.QR)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if receipt.Type != fiscal.TypeIncome {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Можно добавить только чек прихода, а не возврата или расхода"})
+			return
+		}
+
+		expenseInput := input.CreateExpenseInput
+		if expenseInput.Title == "" {
+			expenseInput.Title = "Чек от " + receipt.Time.Format("02.01.2006 15:04")
+		}
+		if expenseInput.PaidBy == 0 {
+			expenseInput.PaidBy = c.GetUint("user_id")
+		}
+		paidAt := receipt.Time
+		expenseInput.PaidAt = &paidAt
+		// С надбавками сумма чека — итог, а подытог задаёт пользователь.
+		expenseInput.Amount = receipt.Sum
+
+		eventID := common.ParseUintParam(c.Param("id"))
+		ref := receipt.Ref()
+		var existing models.Expense
+		err = db.Where("event_id = ? AND fiscal_ref = ?", eventID, ref).First(&existing).Error
+		if err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Этот чек уже добавлен", "expense_id": existing.ID})
+			return
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при проверке чека"})
+			return
+		}
+
+		if c.Query("preview") == "true" {
+			c.JSON(http.StatusOK, gin.H{"receipt": receipt, "fiscal_ref": ref, "expense": expenseInput})
+			return
+		}
+
+		var event models.Event
+		if err := db.First(&event, eventID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Событие не найдено"})
+			return
+		}
+
+		expense := models.Expense{
+			CreatedBy: c.GetUint("user_id"),
+			FiscalRef: &ref,
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return CreateExpense(tx, event, &expense, expenseInput)
+		})
+
+		switch {
+		case errors.Is(err, split.ErrInvalid):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case isDuplicateKey(db, err):
+			// Тот же чек добавили параллельным запросом.
+			c.JSON(http.StatusConflict, gin.H{"error": "Этот чек уже добавлен"})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании траты"})
+		default:
+			c.JSON(http.StatusOK, gin.H{"message": "Трата добавлена", "expense_id": expense.ID, "receipt": receipt})
+		}
+	}
+}
+
+// bindFiscalReceipt читает input из JSON или из multipart-формы с изображением.
+func bindFiscalReceipt(c *gin.Context) (FiscalReceiptInput, error) {
+	var input FiscalReceiptInput
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			return input, err
+		}
+		if input.QR == "" {
+			return input, errors.New("нужна строка QR-кода в поле qr")
+		}
+		return input, nil
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAttachmentSize+1<<20)
+	if raw := c.PostForm("expense"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &input.CreateExpenseInput); err != nil {
+			return input, fmt.Errorf("некорректное поле expense: %v", err)
+		}
+	}
+	if input.QR = c.PostForm("qr"); input.QR != "" {
+		return input, nil
+	}
+
+	header, err := c.FormFile("image")
+	if err != nil {
+		return input, errors.New("нужна строка qr или изображение QR-кода в поле image")
+	}
+	f, err := header.Open()
+	if err != nil {
+		return input, err
+	}
+	defer f.Close()
+	input.QR, err = fiscal.DecodeImage(f)
+	return input, err
+}
@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"split-the-bill/internal/common"
+	"split-the-bill/internal/ledger"
+	"split-the-bill/internal/middleware"
+	"split-the-bill/internal/models"
+)
+
+var errNotGuest = errors.New("user is not a guest of this event")
+
+type AddGuestInput struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// AddGuest добавляет в событие гостя — участника без аккаунта, только с именем.
+func AddGuest(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID := common.ParseUintParam(c.Param("id"))
+
+		var input AddGuestInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+			return
+		}
+
+		var guest models.User
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			guest, err = createGuest(tx, eventID, input.Name)
+			return err
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add guest"})
+			return
+		}
+		c.JSON(http.StatusCreated, guest)
+	}
+}
+
+// createGuest создаёт гостя и добавляет его в событие участником.
+func createGuest(tx *gorm.DB, eventID uint, name string) (models.User, error) {
+	guest := models.User{Name: name, GuestEventID: &eventID}
+	if err := tx.Create(&guest).Error; err != nil {
+		return guest, err
+	}
+	err := tx.Create(&models.EventParticipant{
+		EventID: eventID,
+		UserID:  guest.ID,
+		Role:    models.RoleMember,
+	}).Error
+	return guest, err
+}
+
+func ListGuests(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var guests []models.User
+		db.Where("guest_event_id = ?", c.Param("id")).Order("id").Find(&guests)
+		c.JSON(http.StatusOK, guests)
+	}
+}
+
+type ClaimGuestInput struct {
+	UserID uint `json:"user_id"`
+}
+
+// ClaimGuest — действие админа. Без user_id админ забирает гостя себе; чтобы
+// гостя забрал другой пользователь, тому отправляется персональное приглашение
+// на место гостя, и история переходит к нему только после согласия. Участник
+// забирает гостя себе по приглашению с guest_id, которое создаёт админ.
+func ClaimGuest(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID := common.ParseUintParam(c.Param("id"))
+		guestID := common.ParseUintParam(c.Param("user_id"))
+		caller, _ := middleware.Participant(c)
+
+		var input ClaimGuestInput
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&input); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+				return
+			}
+		}
+		if input.UserID == 0 {
+			input.UserID = caller.UserID
+		}
+
+		var user models.User
+		if err := db.First(&user, input.UserID).Error; err != nil || user.IsGuest() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "guest can only be claimed by a registered user"})
+			return
+		}
+
+		if user.ID != caller.UserID {
+			inviteGuestClaim(c, db, eventID, guestID, user)
+			return
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			return claimGuest(tx, eventID, guestID, user.ID, "")
+		})
+		if errors.Is(err, errNotGuest) || errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": errNotGuest.Error()})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to claim guest"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "guest claimed", "user_id": user.ID})
+	}
+}
+
+// inviteGuestClaim отправляет user персональное приглашение на место гостя.
+func inviteGuestClaim(c *gin.Context, db *gorm.DB, eventID, guestID uint, user models.User) {
+	if user.Email == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user has no email; share a guest invite link instead"})
+		return
+	}
+	var guest models.User
+	if err := db.First(&guest, guestID).Error; err != nil ||
+		guest.GuestEventID == nil || *guest.GuestEventID != eventID {
+		c.JSON(http.StatusNotFound, gin.H{"error": errNotGuest.Error()})
+		return
+	}
+
+	email := normalizeEmail(*user.Email)
+	invite, err := newInvite(eventID, c.GetUint("user_id"), CreateInviteInput{Email: &email, GuestID: &guestID})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := db.Create(&invite).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create invite"})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "user invited to claim the guest", "invite": invite})
+}
+
+// claimGuest сливает гостя события с пользователем userID: все траты, доли и
+// платежи гостя переходят пользователю, гость удаляется, долги пересчитываются.
+// Если пользователь ещё не участник, он занимает место гостя (с ролью role,
+// если она задана).
+func claimGuest(tx *gorm.DB, eventID, guestID, userID uint, role string) error {
+	var guest models.User
+	if err := tx.First(&guest, guestID).Error; err != nil {
+		return err
+	}
+	if guest.GuestEventID == nil || *guest.GuestEventID != eventID {
+		return errNotGuest
+	}
+
+	// Если у пользователя и гостя есть строки с одним владельцем (доли одной
+	// траты, назначения одной позиции), складываем их в строку пользователя,
+	// иначе пользователь окажется в делении дважды.
+	merges := []struct {
+		model  interface{}
+		parent string
+		sums   []string
+	}{
+		{&models.ExpenseShare{}, "expense_id",
+			[]string{"share_amount", "percent", "weight", "subtotal", "tax", "tip", "service", "discount"}},
+		{&models.ExpenseItemAssignment{}, "item_id", []string{"portion"}},
+		{&models.RecurringExpenseShare{}, "recurring_expense_id", []string{"share_amount", "percent", "weight"}},
+	}
+	for _, m := range merges {
+		if err := mergeRows(tx, m.model, m.parent, m.sums, guestID, userID); err != nil {
+			return err
+		}
+	}
+
+	updates := []struct {
+		model  interface{}
+		column string
+	}{
+		{&models.Expense{}, "paid_by"},
+		{&models.Expense{}, "created_by"},
+		{&models.ExpenseShare{}, "user_id"},
+		{&models.ExpenseItemAssignment{}, "user_id"},
+		{&models.Attachment{}, "uploaded_by"},
+		{&models.Payment{}, "from_user"},
+		{&models.Payment{}, "to_user"},
+		{&models.PaymentStatusChange{}, "changed_by"},
+		{&models.Settlement{}, "from_user"},
+		{&models.Settlement{}, "to_user"},
+		{&models.Settlement{}, "created_by"},
+		{&models.Debt{}, "from_user"},
+		{&models.Debt{}, "to_user"},
+		{&models.RecurringExpense{}, "paid_by"},
+		{&models.RecurringExpense{}, "created_by"},
+		{&models.RecurringExpenseShare{}, "user_id"},
+	}
+	for _, u := range updates {
+		if err := tx.Model(u.model).Where(u.column+" = ?", guestID).Update(u.column, userID).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("event_id = ? AND from_user = to_user", eventID).Delete(&models.Debt{}).Error; err != nil {
+		return err
+	}
+	// Платежи между гостем и пользователем стали платежами самому себе.
+	selfPayments := tx.Model(&models.Payment{}).Select("id").Where("event_id = ? AND from_user = to_user", eventID)
+	if err := tx.Where("payment_id IN (?)", selfPayments).Delete(&models.PaymentStatusChange{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("event_id = ? AND from_user = to_user", eventID).Delete(&models.Payment{}).Error; err != nil {
+		return err
+	}
+
+	var existing int64
+	if err := tx.Model(&models.EventParticipant{}).
+		Where("event_id = ? AND user_id = ?", eventID, userID).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		if err := tx.Where("event_id = ? AND user_id = ?", eventID, guestID).Delete(&models.EventParticipant{}).Error; err != nil {
+			return err
+		}
+	} else {
+		changes := map[string]interface{}{"user_id": userID}
+		if role != "" {
+			changes["role"] = role
+		}
+		if err := tx.Model(&models.EventParticipant{}).
+			Where("event_id = ? AND user_id = ?", eventID, guestID).Updates(changes).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&models.EventInvite{}).Where("guest_id = ? AND status = ?", guestID, models.InviteStatusPending).
+		Update("status", models.InviteStatusRevoked).Error; err != nil {
+		return err
+	}
+	if err := tx.Delete(&guest).Error; err != nil {
+		return err
+	}
+	return ledger.Recompute(tx, eventID)
+}
+
+// mergeRows складывает строки гостя в строки пользователя с тем же parent:
+// поля sums суммируются, строка гостя удаляется. Остальные строки гостя
+// переносит на пользователя вызывающий.
+func mergeRows(tx *gorm.DB, model interface{}, parent string, sums []string, guestID, userID uint) error {
+	var rows []map[string]interface{}
+	if err := tx.Model(model).
+		Where("user_id = ? AND "+parent+" IN (?)", guestID,
+			tx.Model(model).Select(parent).Where("user_id = ?", userID)).
+		Find(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		changes := make(map[string]interface{}, len(sums))
+		for _, col := range sums {
+			// Колонки, добавленные миграцией, у старых строк могут быть NULL.
+			add := row[col]
+			if add == nil {
+				add = 0
+			}
+			changes[col] = gorm.Expr("COALESCE("+col+", 0) + ?", add)
+		}
+		if err := tx.Model(model).
+			Where(parent+" = ? AND user_id = ?", row[parent], userID).
+			Updates(changes).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(model, row["id"]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
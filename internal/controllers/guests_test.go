@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"split-the-bill/internal/middleware"
+	"split-the-bill/internal/models"
+	"testing"
+	"time"
+)
+
+// Если пользователь и гость были в одной позиции чека, в одном шаблоне и
+// платили друг другу, после слияния пользователь не должен оказаться в
+// делении дважды, а платежи самому себе — остаться в событии.
+func TestClaimGuestMergesOverlappingRows(t *testing.T) {
+	db := newTestDB(t)
+	alice := createUser(t, db, "Алиса")
+	boris := createUser(t, db, "Борис")
+	event := createEvent(t, db, alice.ID, map[uint]string{boris.ID: models.RoleMember})
+	guest, err := createGuest(db, event.ID, "Гость")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, ev := testRouter(db)
+	ev.POST("/expenses", func(c *gin.Context) { AddExpense(c, db) })
+	ev.POST("/recurring", CreateRecurringExpense(db))
+	ev.POST("/payments", func(c *gin.Context) { AddPayment(c, db) })
+	ev.POST("/guests/:user_id/claim", ClaimGuest(db))
+	r.Group("/expenses/:id", middleware.ExpenseMember(db)).PUT("", UpdateExpense(db))
+
+	post := func(path, body string) {
+		t.Helper()
+		if w := request(t, r, http.MethodPost, path, alice.ID, "application/json", body); w.Code >= 300 {
+			t.Fatalf("POST %s: %d %s", path, w.Code, w.Body)
+		}
+	}
+	post("/events/1/expenses", fmt.Sprintf(`{"title": "Ужин", "paid_by": %d, "items": [
+		{"name": "Пицца", "unit_price": "900.00", "assigned": [{"user_id": %d}, {"user_id": %d}, {"user_id": %d}]},
+		{"name": "Чай", "unit_price": "100.00", "assigned": [{"user_id": %d}]}
+	]}`, alice.ID, alice.ID, guest.ID, boris.ID, guest.ID))
+	post("/events/1/recurring", fmt.Sprintf(`{"title": "Аренда", "amount": "3000.00", "paid_by": %d,
+		"split_mode": "weights", "schedule": "FREQ=MONTHLY",
+		"shares": [{"user_id": %d, "weight": 1}, {"user_id": %d, "weight": 2}, {"user_id": %d, "weight": 3}]}`,
+		alice.ID, alice.ID, guest.ID, boris.ID))
+	post("/events/1/payments", fmt.Sprintf(`{"to_user": %d, "amount": "50.00"}`, guest.ID))
+	post("/events/1/payments", fmt.Sprintf(`{"from_user": %d, "to_user": %d, "amount": "20.00"}`, guest.ID, alice.ID))
+
+	post(fmt.Sprintf("/events/1/guests/%d/claim", guest.ID), "")
+
+	var portions []int64
+	if err := db.Model(&models.ExpenseItemAssignment{}).Where("user_id = ?", alice.ID).
+		Order("item_id").Pluck("portion", &portions).Error; err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(portions) != "[2 1]" {
+		t.Errorf("Алиса's item portions = %v, want [2 1]", portions)
+	}
+
+	var weights []int64
+	if err := db.Model(&models.RecurringExpenseShare{}).Where("user_id = ?", alice.ID).
+		Pluck("weight", &weights).Error; err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(weights) != "[3]" {
+		t.Errorf("Алиса's template weights = %v, want [3]", weights)
+	}
+
+	var selfPayments, history int64
+	db.Model(&models.Payment{}).Where("from_user = to_user").Count(&selfPayments)
+	db.Model(&models.PaymentStatusChange{}).Count(&history)
+	if selfPayments != 0 || history != 0 {
+		t.Errorf("left %d self payments and %d history rows, want none", selfPayments, history)
+	}
+
+	// Трату после слияния можно пересчитать, а шаблон — провести.
+	var expense models.Expense
+	if err := db.First(&expense).Error; err != nil {
+		t.Fatal(err)
+	}
+	w := request(t, r, http.MethodPut, fmt.Sprintf("/expenses/%d", expense.ID), alice.ID, "application/json", `{"title": "Ужин в пиццерии"}`)
+	if w.Code != http.StatusOK {
+		t.Errorf("update merged expense: %d %s", w.Code, w.Body)
+	}
+
+	var rec models.RecurringExpense
+	if err := db.Preload("Shares").First(&rec).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := CreateOccurrence(db, event, rec, time.Now()); err != nil {
+		t.Errorf("materialize merged template: %v", err)
+	}
+}
+
+// Гостем пользователя делает только событие: через POST /users гостевой
+// статус не выставить, иначе аккаунт можно было бы выдать за чужого гостя.
+func TestCreateUserIgnoresGuestEvent(t *testing.T) {
+	db := newTestDB(t)
+	alice := createUser(t, db, "Алиса")
+	event := createEvent(t, db, alice.ID, nil)
+
+	r, _ := testRouter(db)
+	r.POST("/users", CreateUser(db))
+	body := fmt.Sprintf(`{"name": "Мэллори", "email": "mallory@example.com", "password": "secret",
+		"ID": %d, "guest_event_id": %d, "is_admin": true}`, alice.ID, event.ID)
+	if w := request(t, r, http.MethodPost, "/users", 0, "application/json", body); w.Code != http.StatusOK {
+		t.Fatalf("POST /users: %d %s", w.Code, w.Body)
+	}
+
+	var user models.User
+	if err := db.Where("email = ?", "mallory@example.com").First(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	if user.ID == alice.ID || user.IsGuest() || user.IsAdmin {
+		t.Errorf("created user %+v, want a new regular user", user)
+	}
+	if user.Password == "secret" {
+		t.Error("password is stored in plain text")
+	}
+}
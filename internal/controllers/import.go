@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"split-the-bill/internal/common"
+	"split-the-bill/internal/importer"
+	"split-the-bill/internal/ledger"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/split"
+	"strings"
+)
+
+const maxImportSize = 5 << 20
+
+var (
+	errImportRow    = errors.New("invalid import row")
+	errImportFailed = errors.New("import has errors")
+	errDryRun       = errors.New("dry run")
+)
+
+// ImportReport — результат импорта или его пробного прогона.
+type ImportReport struct {
+	Format   importer.Format     `json:"format"`
+	Rows     []importer.Row      `json:"rows"`
+	Errors   []importer.RowError `json:"errors"`
+	Guests   []string            `json:"guests"`
+	Expenses int                 `json:"expenses"`
+	Payments int                 `json:"payments"`
+	DryRun   bool                `json:"dry_run"`
+}
+
+// ImportExpenses импортирует траты и платежи из CSV: собственного формата
+// (см. пакет importer), экспорта Splitwise или Tricount. Файл передаётся телом
+// запроса или в multipart-поле file; формат определяется по заголовку или
+// задаётся ?format=. Имена сопоставляются с участниками события по имени
+// пользователя без учёта регистра либо явно через mapping — JSON вида
+// {"Имя в файле": user_id} в одноимённом поле формы. Для остальных имён
+// создаются гости.
+//
+// Все строки проводятся через ту же логику, что и AddExpense, в одной
+// транзакции: при любой ошибке не сохраняется ничего, а в ответе перечислены
+// ошибки по строкам. С ?dry_run=true импорт прогоняется и откатывается.
+func ImportExpenses(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID := common.ParseUintParam(c.Param("id"))
+		dryRun := c.Query("dry_run") == "true"
+
+		file, mapping, err := readImport(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		defer file.Close()
+
+		result, err := importer.Parse(file, importer.Format(c.Query("format")))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		var event models.Event
+		if err := db.First(&event, eventID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Событие не найдено"})
+			return
+		}
+
+		report := ImportReport{
+			Format: result.Format,
+			Rows:   result.Rows,
+			Errors: result.Errors,
+			DryRun: dryRun,
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			users, err := importParticipants(tx, eventID, result.Names(), mapping, &report)
+			if err != nil {
+				return err
+			}
+
+			for _, row := range result.Rows {
+				if err := tx.SavePoint("import_row").Error; err != nil {
+					return err
+				}
+				err := importRow(tx, event, users, row, c.GetUint("user_id"))
+				if errors.Is(err, split.ErrInvalid) || errors.Is(err, errImportRow) {
+					if err := tx.RollbackTo("import_row").Error; err != nil {
+						return err
+					}
+					report.Errors = append(report.Errors, importer.RowError{Line: row.Line, Error: err.Error()})
+					continue
+				} else if err != nil {
+					return err
+				}
+
+				if row.Kind == importer.KindPayment {
+					report.Payments++
+				} else {
+					report.Expenses++
+				}
+			}
+
+			if len(report.Errors) > 0 {
+				return errImportFailed
+			}
+			if err := ledger.Recompute(tx, eventID); err != nil {
+				return err
+			}
+			if dryRun {
+				return errDryRun
+			}
+			return nil
+		})
+
+		switch {
+		case errors.Is(err, errDryRun), dryRun && errors.Is(err, errImportFailed):
+			c.JSON(http.StatusOK, report)
+		case errors.Is(err, errImportFailed):
+			c.JSON(http.StatusUnprocessableEntity, report)
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при импорте"})
+		default:
+			c.JSON(http.StatusCreated, report)
+		}
+	}
+}
+
+// readImport возвращает CSV из тела запроса или из multipart-формы и mapping имён.
+func readImport(c *gin.Context) (io.ReadCloser, map[string]uint, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return c.Request.Body, nil, nil
+	}
+
+	var mapping map[string]uint
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			return nil, nil, fmt.Errorf("некорректное поле mapping: %v", err)
+		}
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		return nil, nil, errors.New("нужен CSV-файл в поле file")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, mapping, nil
+}
+
+// importParticipants сопоставляет имена из файла с участниками события и
+// создаёт гостей для тех, кого найти не удалось.
+func importParticipants(tx *gorm.DB, eventID uint, names []string, mapping map[string]uint, report *ImportReport) (map[string]uint, error) {
+	var participants []models.User
+	if err := tx.Model(&models.User{}).
+		Joins("JOIN event_participants ON event_participants.user_id = users.id").
+		Where("event_participants.event_id = ?", eventID).
+		Find(&participants).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]bool, len(participants))
+	byName := make(map[string][]uint)
+	for _, p := range participants {
+		byID[p.ID] = true
+		key := strings.ToLower(strings.TrimSpace(p.Name))
+		byName[key] = append(byName[key], p.ID)
+	}
+
+	users := make(map[string]uint, len(names))
+	for _, name := range names {
+		if id, ok := mapping[name]; ok {
+			if !byID[id] {
+				report.Errors = append(report.Errors, importer.RowError{
+					Error: fmt.Sprintf("mapping: пользователь %d не участвует в событии", id),
+				})
+				continue
+			}
+			users[name] = id
+			continue
+		}
+
+		switch ids := byName[strings.ToLower(name)]; len(ids) {
+		case 0:
+			guest, err := createGuest(tx, eventID, name)
+			if err != nil {
+				return nil, err
+			}
+			users[name] = guest.ID
+			report.Guests = append(report.Guests, name)
+		case 1:
+			users[name] = ids[0]
+		default:
+			report.Errors = append(report.Errors, importer.RowError{
+				Error: fmt.Sprintf("имя %q есть у нескольких участников, укажите его в mapping", name),
+			})
+		}
+	}
+	return users, nil
+}
+
+// importRow сохраняет одну строку: трату — через saveExpense, как AddExpense,
+// платёж — записью Payment со статусом по правилам AddPayment. Долги
+// пересчитываются один раз после всех строк.
+func importRow(tx *gorm.DB, event models.Event, users map[string]uint, row importer.Row, createdBy uint) error {
+	user := func(name string) (uint, error) {
+		if id, ok := users[name]; ok {
+			return id, nil
+		}
+		return 0, fmt.Errorf("%w: участник %q не сопоставлен", errImportRow, name)
+	}
+	paidBy, err := user(row.PaidBy)
+	if err != nil {
+		return err
+	}
+
+	if row.Kind == importer.KindPayment {
+		to, err := user(row.To)
+		if err != nil {
+			return err
+		}
+		if to == paidBy {
+			return fmt.Errorf("%w: платёж самому себе", errImportRow)
+		}
+		// Как и в AddPayment, сразу засчитывается только платёж, который
+		// импортирует сам получатель (или админ за гостя); остальные ждут
+		// подтверждения получателя.
+		payment := models.Payment{
+			EventID:  event.ID,
+			FromUser: paidBy,
+			ToUser:   to,
+			Amount:   row.Amount,
+			PaidAt:   row.Date,
+			Status:   models.PaymentPending,
+		}
+		if confirms, err := confirmsFor(tx, event.ID, to, createdBy); err != nil {
+			return err
+		} else if confirms {
+			payment.Status = models.PaymentConfirmed
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		return recordPaymentStatus(tx, &payment, createdBy, "")
+	}
+
+	input := CreateExpenseInput{
+		Title:  row.Title,
+		Amount: row.Amount,
+		PaidBy: paidBy,
+		PaidAt: &row.Date,
+	}
+	if len(row.Shares) > 0 {
+		input.SplitMode = split.ModeExact
+		for _, s := range row.Shares {
+			id, err := user(s.Name)
+			if err != nil {
+				return err
+			}
+			input.Shares = append(input.Shares, ShareInput{UserID: id, ShareAmount: s.Amount})
+		}
+	}
+
+	expense := models.Expense{EventID: event.ID, CreatedBy: createdBy}
+	rem, err := newRemainder(tx, event, paidBy)
+	if err != nil {
+		return err
+	}
+	return saveExpense(tx, &expense, input, rem)
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+	"reflect"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+	"testing"
+)
+
+// Импортированный платёж между другими участниками не гасит долг, пока его
+// не подтвердит получатель; платёж самому импортёру засчитывается сразу.
+func TestImportPaymentWaitsForPayee(t *testing.T) {
+	db := newTestDB(t)
+	alice := createUser(t, db, "Алиса")
+	boris := createUser(t, db, "Борис")
+	vera := createUser(t, db, "Вера")
+	event := createEvent(t, db, alice.ID, map[uint]string{boris.ID: models.RoleMember, vera.ID: models.RoleMember})
+
+	r, ev := testRouter(db)
+	ev.POST("/import", ImportExpenses(db))
+	ev.POST("/payments/:payment_id/confirm", ConfirmPayment(db))
+
+	csv := "date,title,amount,paid_by,type,to\n" +
+		"2026-10-17,Ужин,900,Борис,,\n" +
+		"2026-10-18,Возврат,300,Вера,payment,Борис\n" +
+		"2026-10-19,Такси,100,Борис,payment,Алиса\n"
+	w := request(t, r, http.MethodPost, "/events/1/import", alice.ID, "text/csv", csv)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("import: %d %s", w.Code, w.Body)
+	}
+
+	var payments []models.Payment
+	if err := db.Preload("History").Order("id").Find(&payments).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(payments) != 2 {
+		t.Fatalf("got %d payments, want 2", len(payments))
+	}
+	if p := payments[0]; p.Status != models.PaymentPending || len(p.History) != 1 || p.History[0].ChangedBy != alice.ID {
+		t.Errorf("payment to Борис = %+v, want pending with history by importer", p)
+	}
+	if p := payments[1]; p.Status != models.PaymentConfirmed || len(p.History) != 1 {
+		t.Errorf("payment to importer = %+v, want confirmed with history", p)
+	}
+
+	// Ужин 900 на троих: Алиса и Вера должны Борису по 300, а перевод Бориса
+	// Алисе, подтверждённый ею при импорте, увеличивает её долг до 400.
+	want := map[[2]uint]money.Money{
+		{alice.ID, boris.ID}: 40000,
+		{vera.ID, boris.ID}:  30000,
+	}
+	if got := openDebts(t, db, event.ID); !reflect.DeepEqual(got, want) {
+		t.Errorf("debts before confirmation = %v, want %v", got, want)
+	}
+
+	path := "/events/1/payments/" + itoa(payments[0].ID) + "/confirm"
+	if w := request(t, r, http.MethodPost, path, alice.ID, "", ""); w.Code != http.StatusForbidden {
+		t.Errorf("importer confirmed someone else's payment: %d %s", w.Code, w.Body)
+	}
+	if w := request(t, r, http.MethodPost, path, boris.ID, "", ""); w.Code != http.StatusOK {
+		t.Fatalf("confirm: %d %s", w.Code, w.Body)
+	}
+	delete(want, [2]uint{vera.ID, boris.ID})
+	if got := openDebts(t, db, event.ID); !reflect.DeepEqual(got, want) {
+		t.Errorf("debts after confirmation = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,329 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"split-the-bill/internal/common"
+	"split-the-bill/internal/models"
+	"strings"
+	"time"
+)
+
+const defaultInviteTTL = 7 * 24 * time.Hour
+
+var (
+	errInviteUnavailable = errors.New("invite is no longer valid")
+	errInviteNotYours    = errors.New("invite is addressed to another email")
+	errAlreadyMember     = errors.New("user already a participant")
+)
+
+type CreateInviteInput struct {
+	Email          *string `json:"email" binding:"omitempty,email"`
+	Role           string  `json:"role"`
+	GuestID        *uint   `json:"guest_id"`
+	ExpiresInHours int     `json:"expires_in_hours"`
+	SingleUse      bool    `json:"single_use"`
+	MaxUses        int     `json:"max_uses"`
+}
+
+func CreateInvite(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input CreateInviteInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		eventID := common.ParseUintParam(c.Param("id"))
+		invite, err := newInvite(eventID, c.GetUint("user_id"), input)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if input.GuestID != nil {
+			var guest models.User
+			if err := db.First(&guest, *input.GuestID).Error; err != nil ||
+				guest.GuestEventID == nil || *guest.GuestEventID != eventID {
+				c.JSON(http.StatusBadRequest, gin.H{"error": errNotGuest.Error()})
+				return
+			}
+			// Гостя может забрать только один человек.
+			invite.MaxUses = 1
+		}
+		if err := db.Create(&invite).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create invite"})
+			return
+		}
+		c.JSON(http.StatusCreated, invite)
+	}
+}
+
+func ListInvites(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var invites []models.EventInvite
+		db.Where("event_id = ?", c.Param("id")).Order("id DESC").Find(&invites)
+		c.JSON(http.StatusOK, invites)
+	}
+}
+
+func RevokeInvite(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res := db.Model(&models.EventInvite{}).
+			Where("id = ? AND event_id = ? AND status = ?", c.Param("invite_id"), c.Param("id"), models.InviteStatusPending).
+			Update("status", models.InviteStatusRevoked)
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke invite"})
+			return
+		}
+		if res.RowsAffected == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "pending invite not found"})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// ListMyInvites возвращает ожидающие персональные приглашения на email текущего пользователя.
+func ListMyInvites(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		if err := db.First(&user, c.GetUint("user_id")).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+
+		invites := []models.EventInvite{}
+		if user.Email != nil {
+			db.Where("email = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)",
+				normalizeEmail(*user.Email), models.InviteStatusPending, time.Now()).
+				Order("id DESC").Find(&invites)
+		}
+		c.JSON(http.StatusOK, invites)
+	}
+}
+
+// GetInvite показывает приглашение по токену, чтобы приглашённый видел, куда его зовут.
+func GetInvite(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var invite models.EventInvite
+		if err := db.Where("token = ?", c.Param("token")).First(&invite).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "invite not found"})
+			return
+		}
+		var event models.Event
+		if err := db.First(&event, invite.EventID).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"invite": invite, "event": event})
+	}
+}
+
+func AcceptInvite(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var participant models.EventParticipant
+		err := db.Transaction(func(tx *gorm.DB) error {
+			invite, user, err := loadInviteForUser(tx, c.Param("token"), c.GetUint("user_id"))
+			if err != nil {
+				return err
+			}
+			participant, err = acceptInvite(tx, invite, user.ID)
+			return err
+		})
+		if err != nil {
+			respondInviteError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, participant)
+	}
+}
+
+func DeclineInvite(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			invite, _, err := loadInviteForUser(tx, c.Param("token"), c.GetUint("user_id"))
+			if err != nil {
+				return err
+			}
+			if invite.Email == nil {
+				// Ссылку-приглашение отклонять не нужно — ею просто не пользуются.
+				return errInviteNotYours
+			}
+			return tx.Model(&invite).Update("status", models.InviteStatusDeclined).Error
+		})
+		if err != nil {
+			respondInviteError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func newInvite(eventID, createdBy uint, input CreateInviteInput) (models.EventInvite, error) {
+	if input.Role == "" {
+		input.Role = models.RoleMember
+	}
+	if !models.ValidRole(input.Role) || input.Role == models.RoleOwner {
+		return models.EventInvite{}, errors.New("invalid role")
+	}
+	if input.MaxUses < 0 || input.ExpiresInHours < 0 {
+		return models.EventInvite{}, errors.New("max_uses and expires_in_hours must not be negative")
+	}
+
+	token, err := newInviteToken()
+	if err != nil {
+		return models.EventInvite{}, err
+	}
+
+	ttl := defaultInviteTTL
+	if input.ExpiresInHours > 0 {
+		ttl = time.Duration(input.ExpiresInHours) * time.Hour
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	invite := models.EventInvite{
+		EventID:   eventID,
+		Token:     token,
+		Role:      input.Role,
+		GuestID:   input.GuestID,
+		MaxUses:   input.MaxUses,
+		Status:    models.InviteStatusPending,
+		ExpiresAt: &expiresAt,
+		CreatedBy: createdBy,
+	}
+	if input.SingleUse {
+		invite.MaxUses = 1
+	}
+	if input.Email != nil {
+		email := normalizeEmail(*input.Email)
+		invite.Email = &email
+		invite.MaxUses = 1
+	}
+	return invite, nil
+}
+
+func newInviteToken() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b), nil
+}
+
+func loadInviteForUser(tx *gorm.DB, token string, userID uint) (models.EventInvite, models.User, error) {
+	var invite models.EventInvite
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token = ?", token).First(&invite).Error; err != nil {
+		return invite, models.User{}, err
+	}
+	var user models.User
+	if err := tx.First(&user, userID).Error; err != nil {
+		return invite, user, err
+	}
+
+	if invite.Status != models.InviteStatusPending || invite.Expired(time.Now()) {
+		return invite, user, errInviteUnavailable
+	}
+	if invite.Email != nil && (user.Email == nil || normalizeEmail(*user.Email) != *invite.Email) {
+		return invite, user, errInviteNotYours
+	}
+	return invite, user, nil
+}
+
+// acceptInvite добавляет пользователя в событие по приглашению и учитывает
+// использование. Приглашение на место гостя сливает гостя с пользователем.
+func acceptInvite(tx *gorm.DB, invite models.EventInvite, userID uint) (models.EventParticipant, error) {
+	participant := models.EventParticipant{
+		EventID: invite.EventID,
+		UserID:  userID,
+		Role:    invite.Role,
+	}
+
+	if invite.GuestID == nil {
+		var existing int64
+		if err := tx.Model(&models.EventParticipant{}).
+			Where("event_id = ? AND user_id = ?", invite.EventID, userID).
+			Count(&existing).Error; err != nil {
+			return participant, err
+		}
+		if existing > 0 {
+			return participant, errAlreadyMember
+		}
+	}
+
+	// Использование учитывается условным UPDATE: из параллельных принятий
+	// одноразовой ссылки пройдёт только одно.
+	res := tx.Model(&models.EventInvite{}).
+		Where("id = ? AND status = ? AND (max_uses = 0 OR uses < max_uses)", invite.ID, models.InviteStatusPending).
+		Update("uses", gorm.Expr("uses + 1"))
+	if res.Error != nil {
+		return participant, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return participant, errInviteUnavailable
+	}
+	if err := tx.Model(&models.EventInvite{}).
+		Where("id = ? AND max_uses > 0 AND uses >= max_uses", invite.ID).
+		Update("status", models.InviteStatusAccepted).Error; err != nil {
+		return participant, err
+	}
+
+	if invite.GuestID == nil {
+		err := tx.Create(&participant).Error
+		return participant, err
+	}
+	if err := claimGuest(tx, invite.EventID, *invite.GuestID, userID, invite.Role); err != nil {
+		return participant, err
+	}
+	err := tx.Where("event_id = ? AND user_id = ?", invite.EventID, userID).First(&participant).Error
+	return participant, err
+}
+
+// attachPendingInvites принимает все действующие персональные приглашения
+// на email только что зарегистрированного пользователя.
+func attachPendingInvites(tx *gorm.DB, user models.User) error {
+	if user.Email == nil {
+		return nil
+	}
+
+	var invites []models.EventInvite
+	if err := tx.Where("email = ? AND status = ? AND (expires_at IS NULL OR expires_at > ?)",
+		normalizeEmail(*user.Email), models.InviteStatusPending, time.Now()).
+		Find(&invites).Error; err != nil {
+		return err
+	}
+
+	for _, invite := range invites {
+		if _, err := acceptInvite(tx, invite, user.ID); err != nil &&
+			!errors.Is(err, errAlreadyMember) && !errors.Is(err, errInviteUnavailable) {
+			return err
+		}
+	}
+	return nil
+}
+
+func respondInviteError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "invite not found"})
+	case errors.Is(err, errInviteUnavailable):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, errInviteNotYours):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, errNotGuest):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, errAlreadyMember):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process invite"})
+	}
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
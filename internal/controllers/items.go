@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"math"
+	"math/bits"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+	"split-the-bill/internal/split"
+)
+
+// ItemInput — позиция чека. Без assigned позиция делится на всех участников события.
+type ItemInput struct {
+	Name      string            `json:"name"`
+	Quantity  int64             `json:"quantity"`
+	UnitPrice money.Money       `json:"unit_price"`
+	Assigned  []AssignmentInput `json:"assigned"`
+}
+
+// AssignmentInput — кто ел позицию; portion задаёт долю (по умолчанию 1).
+type AssignmentInput struct {
+	UserID  uint  `json:"user_id"`
+	Portion int64 `json:"portion"`
+}
+
+// buildItems превращает позиции из input в модели и считает их стоимость.
+func buildItems(inputs []ItemInput) ([]models.ExpenseItem, money.Money, error) {
+	var total money.Money
+	items := make([]models.ExpenseItem, len(inputs))
+	for i, in := range inputs {
+		quantity := in.Quantity
+		if quantity == 0 {
+			quantity = 1
+		}
+		if quantity < 0 || in.UnitPrice < 0 {
+			return nil, 0, fmt.Errorf("%w: позиция %q с отрицательным количеством или ценой", split.ErrInvalid, in.Name)
+		}
+
+		hi, itemTotal := bits.Mul64(uint64(in.UnitPrice), uint64(quantity))
+		if hi != 0 || itemTotal > math.MaxInt64-uint64(total) {
+			return nil, 0, fmt.Errorf("%w: слишком большая стоимость позиции %q", split.ErrInvalid, in.Name)
+		}
+
+		item := models.ExpenseItem{
+			Name:      in.Name,
+			Quantity:  quantity,
+			UnitPrice: in.UnitPrice,
+			Total:     money.Money(itemTotal),
+		}
+		for _, a := range in.Assigned {
+			portion := a.Portion
+			if portion == 0 {
+				portion = 1
+			}
+			if portion < 0 {
+				return nil, 0, fmt.Errorf("%w: отрицательная доля в позиции %q", split.ErrInvalid, in.Name)
+			}
+			item.Assignments = append(item.Assignments, models.ExpenseItemAssignment{
+				UserID:  a.UserID,
+				Portion: portion,
+			})
+		}
+
+		items[i] = item
+		total += item.Total
+	}
+	return items, total, nil
+}
+
+// itemizedShares считает доли по позициям чека; их сумма должна совпасть с суммой траты.
+func itemizedShares(items []models.ExpenseItem, total money.Money, participants []uint, rem split.Remainder) ([]split.Share, error) {
+	var itemsTotal money.Money
+	splitItems := make([]split.Item, len(items))
+	for i, item := range items {
+		itemsTotal += item.Total
+		splitItems[i].Total = item.Total
+		for _, a := range item.Assignments {
+			splitItems[i].Users = append(splitItems[i].Users, a.UserID)
+			splitItems[i].Portions = append(splitItems[i].Portions, a.Portion)
+		}
+	}
+	if itemsTotal != total {
+		return nil, fmt.Errorf("%w: позиции чека в сумме дают %s, а не %s", split.ErrInvalid, itemsTotal, total)
+	}
+	return split.Items(splitItems, participants, rem)
+}
+
+// replaceItems заменяет позиции траты новыми.
+func replaceItems(tx *gorm.DB, expense *models.Expense, items []models.ExpenseItem) error {
+	if err := tx.Where("item_id IN (?)",
+		tx.Model(&models.ExpenseItem{}).Select("id").Where("expense_id = ?", expense.ID)).
+		Delete(&models.ExpenseItemAssignment{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("expense_id = ?", expense.ID).Delete(&models.ExpenseItem{}).Error; err != nil {
+		return err
+	}
+
+	for i := range items {
+		items[i].ExpenseID = expense.ID
+	}
+	if len(items) > 0 {
+		if err := tx.Create(&items).Error; err != nil {
+			return err
+		}
+	}
+	expense.Items = items
+	return nil
+}
+
+func itemInputs(items []models.ExpenseItem) []ItemInput {
+	inputs := make([]ItemInput, len(items))
+	for i, item := range items {
+		inputs[i] = ItemInput{
+			Name:      item.Name,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		}
+		for _, a := range item.Assignments {
+			inputs[i].Assigned = append(inputs[i].Assigned, AssignmentInput{UserID: a.UserID, Portion: a.Portion})
+		}
+	}
+	return inputs
+}
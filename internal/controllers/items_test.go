@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"errors"
+	"math"
+	"split-the-bill/internal/money"
+	"split-the-bill/internal/split"
+	"testing"
+)
+
+func TestBuildItems(t *testing.T) {
+	items, total, err := buildItems([]ItemInput{
+		{Name: "Пицца", Quantity: 2, UnitPrice: 65000},
+		{Name: "Чай", UnitPrice: 12000, Assigned: []AssignmentInput{{UserID: 1}, {UserID: 2, Portion: 3}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 142000 {
+		t.Errorf("total = %d, want 142000", total)
+	}
+	if items[0].Total != 130000 || items[1].Quantity != 1 || items[1].Total != 12000 {
+		t.Errorf("items = %+v", items)
+	}
+	if p := items[1].Assignments; len(p) != 2 || p[0].Portion != 1 || p[1].Portion != 3 {
+		t.Errorf("assignments = %+v", p)
+	}
+}
+
+func TestBuildItemsInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []ItemInput
+	}{
+		{"negative quantity", []ItemInput{{Quantity: -1, UnitPrice: 100}}},
+		{"negative price", []ItemInput{{UnitPrice: -100}}},
+		{"negative portion", []ItemInput{{UnitPrice: 100, Assigned: []AssignmentInput{{UserID: 1, Portion: -1}}}}},
+		{"price times quantity overflows", []ItemInput{{Quantity: 3, UnitPrice: money.Money(math.MaxInt64 / 2)}}},
+		{"huge quantity", []ItemInput{{Quantity: math.MaxInt64, UnitPrice: 2}}},
+		{"items total overflows", []ItemInput{
+			{UnitPrice: money.Money(math.MaxInt64 - 10)},
+			{UnitPrice: 11},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, total, err := buildItems(tt.items); !errors.Is(err, split.ErrInvalid) {
+				t.Errorf("buildItems() = %d, %v, want ErrInvalid", total, err)
+			}
+		})
+	}
+}
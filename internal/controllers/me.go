@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"sort"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultActivityLimit = 20
+	maxActivityLimit     = 100
+)
+
+// BalanceTotals — сколько пользователь должен и сколько должны ему.
+// Суммы в разных валютах не складываются, поэтому итоги идут по валютам.
+type BalanceTotals struct {
+	Currency string      `json:"currency"`
+	Owe      money.Money `json:"owe"`
+	Owed     money.Money `json:"owed"`
+	Net      money.Money `json:"net"`
+}
+
+type CounterpartBalance struct {
+	UserID uint   `json:"user_id"`
+	Name   string `json:"name"`
+	BalanceTotals
+}
+
+type EventBalance struct {
+	EventID   uint   `json:"event_id"`
+	EventName string `json:"event_name"`
+	BalanceTotals
+}
+
+// Activity — трата или платёж с участием пользователя.
+type Activity struct {
+	Kind      string      `json:"kind"`
+	ID        uint        `json:"id"`
+	EventID   uint        `json:"event_id"`
+	EventName string      `json:"event_name"`
+	Currency  string      `json:"currency"`
+	Title     string      `json:"title,omitempty"`
+	Amount    money.Money `json:"amount"`
+	MyShare   money.Money `json:"my_share,omitempty"`
+	PaidBy    uint        `json:"paid_by,omitempty"`
+	FromUser  uint        `json:"from_user,omitempty"`
+	ToUser    uint        `json:"to_user,omitempty"`
+	Status    string      `json:"status,omitempty"`
+	At        time.Time   `json:"at"`
+}
+
+func (b *BalanceTotals) add(amount money.Money) {
+	if amount < 0 {
+		b.Owe -= amount
+	} else {
+		b.Owed += amount
+	}
+	b.Net += amount
+}
+
+// GetMyBalances — сводка пользователя по всем его событиям: итоги, разбивка
+// по людям и по событиям (из открытых долгов) и последние траты и платежи
+// (?limit=, по умолчанию 20). Читает только строки с участием пользователя,
+// поэтому не замедляется с ростом числа его событий.
+func GetMyBalances(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("user_id")
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultActivityLimit)))
+		if err != nil || limit <= 0 || limit > maxActivityLimit {
+			limit = defaultActivityLimit
+		}
+
+		type debtRow struct {
+			EventID   uint
+			EventName string
+			Currency  string
+			FromUser  uint
+			ToUser    uint
+			Amount    money.Money
+		}
+		var debts []debtRow
+		if err := db.Table("debts").
+			Select("debts.event_id, events.name AS event_name, events.currency, debts.from_user, debts.to_user, debts.amount").
+			Joins("JOIN events ON events.id = debts.event_id").
+			Where("debts.is_settled = false AND debts.amount > 0 AND (debts.from_user = ? OR debts.to_user = ?)", userID, userID).
+			Scan(&debts).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при подсчёте балансов"})
+			return
+		}
+
+		totals := make(map[string]*BalanceTotals)
+		byUser := make(map[uint]map[string]*BalanceTotals)
+		byEvent := make(map[uint]*EventBalance)
+		for _, d := range debts {
+			// Положительная сумма — должны пользователю, отрицательная — должен он.
+			amount, counterpart := d.Amount, d.FromUser
+			if d.FromUser == userID {
+				amount, counterpart = -d.Amount, d.ToUser
+			}
+
+			if totals[d.Currency] == nil {
+				totals[d.Currency] = &BalanceTotals{Currency: d.Currency}
+			}
+			totals[d.Currency].add(amount)
+
+			if byUser[counterpart] == nil {
+				byUser[counterpart] = make(map[string]*BalanceTotals)
+			}
+			if byUser[counterpart][d.Currency] == nil {
+				byUser[counterpart][d.Currency] = &BalanceTotals{Currency: d.Currency}
+			}
+			byUser[counterpart][d.Currency].add(amount)
+
+			if byEvent[d.EventID] == nil {
+				byEvent[d.EventID] = &EventBalance{
+					EventID:       d.EventID,
+					EventName:     d.EventName,
+					BalanceTotals: BalanceTotals{Currency: d.Currency},
+				}
+			}
+			byEvent[d.EventID].add(amount)
+		}
+
+		counterparts := make([]uint, 0, len(byUser))
+		for id := range byUser {
+			counterparts = append(counterparts, id)
+		}
+		names, err := userNames(db, counterparts)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при подсчёте балансов"})
+			return
+		}
+
+		totalList := make([]BalanceTotals, 0, len(totals))
+		for _, t := range totals {
+			totalList = append(totalList, *t)
+		}
+		sort.Slice(totalList, func(i, j int) bool { return totalList[i].Currency < totalList[j].Currency })
+
+		userList := make([]CounterpartBalance, 0, len(byUser))
+		for id, currencies := range byUser {
+			for _, t := range currencies {
+				if t.Net != 0 {
+					userList = append(userList, CounterpartBalance{UserID: id, Name: names[id], BalanceTotals: *t})
+				}
+			}
+		}
+		sort.Slice(userList, func(i, j int) bool {
+			a, b := userList[i], userList[j]
+			if a.Net.Abs() != b.Net.Abs() {
+				return a.Net.Abs() > b.Net.Abs()
+			}
+			if a.UserID != b.UserID {
+				return a.UserID < b.UserID
+			}
+			return a.Currency < b.Currency
+		})
+
+		eventList := make([]EventBalance, 0, len(byEvent))
+		for _, e := range byEvent {
+			eventList = append(eventList, *e)
+		}
+		sort.Slice(eventList, func(i, j int) bool {
+			a, b := eventList[i], eventList[j]
+			if a.Net.Abs() != b.Net.Abs() {
+				return a.Net.Abs() > b.Net.Abs()
+			}
+			return a.EventID < b.EventID
+		})
+
+		activity, err := recentActivity(db, userID, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при загрузке последних операций"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"totals":   totalList,
+			"by_user":  userList,
+			"by_event": eventList,
+			"recent":   activity,
+		})
+	}
+}
+
+// recentActivity возвращает последние limit трат и платежей пользователя по
+// всем его событиям: траты, которые он оплатил или в которых у него есть доля,
+// и платежи от него или ему.
+func recentActivity(db *gorm.DB, userID uint, limit int) ([]Activity, error) {
+	var expenses []Activity
+	if err := db.Table("expenses").
+		Select("'expense' AS kind, expenses.id, expenses.event_id, events.name AS event_name, events.currency, "+
+			"expenses.title, expenses.amount, COALESCE(expense_shares.share_amount, 0) AS my_share, "+
+			"expenses.paid_by, expenses.paid_at AS at").
+		Joins("JOIN events ON events.id = expenses.event_id").
+		Joins("LEFT JOIN expense_shares ON expense_shares.expense_id = expenses.id AND expense_shares.user_id = ?", userID).
+		Where("expenses.event_id IN (?)", db.Model(&models.EventParticipant{}).Select("event_id").Where("user_id = ?", userID)).
+		Where("expenses.paid_by = ? OR expense_shares.id IS NOT NULL", userID).
+		Order("expenses.paid_at DESC, expenses.id DESC").Limit(limit).
+		Scan(&expenses).Error; err != nil {
+		return nil, err
+	}
+
+	var payments []Activity
+	if err := db.Table("payments").
+		Select("'payment' AS kind, payments.id, payments.event_id, events.name AS event_name, events.currency, "+
+			"payments.amount, payments.from_user, payments.to_user, payments.status, payments.paid_at AS at").
+		Joins("JOIN events ON events.id = payments.event_id").
+		Where("payments.from_user = ? OR payments.to_user = ?", userID, userID).
+		Order("payments.paid_at DESC, payments.id DESC").Limit(limit).
+		Scan(&payments).Error; err != nil {
+		return nil, err
+	}
+
+	activity := append(expenses, payments...)
+	sort.SliceStable(activity, func(i, j int) bool { return activity[i].At.After(activity[j].At) })
+	if len(activity) > limit {
+		activity = activity[:limit]
+	}
+	return activity, nil
+}
+
+// userNames возвращает отображаемые имена пользователей по id.
+func userNames(db *gorm.DB, ids []uint) (map[uint]string, error) {
+	names := make(map[uint]string, len(ids))
+	if len(ids) == 0 {
+		return names, nil
+	}
+	var users []models.User
+	if err := db.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		names[u.ID] = displayName(u)
+	}
+	return names, nil
+}
@@ -0,0 +1,278 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"sort"
+	"split-the-bill/internal/common"
+	"split-the-bill/internal/ledger"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+	"time"
+)
+
+var (
+	errNothingToSettle   = errors.New("nothing to settle")
+	errManyCurrencies    = errors.New("debts in several currencies")
+	errStaleNetting      = errors.New("netting changed")
+	errPendingSettlement = errors.New("settlement awaits confirmation")
+)
+
+// PairDebt — открытый долг между двумя пользователями в одном событии.
+// Amount положителен, если второй пользователь должен первому.
+type PairDebt struct {
+	DebtID    uint        `json:"debt_id"`
+	EventID   uint        `json:"event_id"`
+	EventName string      `json:"event_name"`
+	Currency  string      `json:"currency"`
+	FromUser  uint        `json:"from_user"`
+	ToUser    uint        `json:"to_user"`
+	Amount    money.Money `json:"amount"`
+	// Role — роль вызывающего в событии. Платежи записывают участники не ниже
+	// member, поэтому долги из событий, где он только зритель, во взаимозачёт
+	// не попадают.
+	Role string `json:"role"`
+}
+
+// PairNet — итог по одной валюте и единственный перевод, закрывающий все
+// долги пары в этой валюте.
+type PairNet struct {
+	BalanceTotals
+	Transfer *ledger.Transfer `json:"transfer"`
+}
+
+// GetPairBalance показывает долги между вызывающим и :user_id во всех общих
+// событиях и их взаимозачёт по каждой валюте — тот, что проведёт SettlePair.
+func GetPairBalance(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("user_id")
+		otherID := common.ParseUintParam(c.Param("user_id"))
+		if otherID == 0 || otherID == userID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Нужен другой пользователь"})
+			return
+		}
+
+		debts, err := pairDebts(db, userID, otherID, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при подсчёте долгов"})
+			return
+		}
+		names, err := userNames(db, []uint{otherID})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при подсчёте долгов"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"user_id": otherID,
+			"name":    names[otherID],
+			"debts":   debts,
+			"net":     netPair(settleable(debts), userID, otherID),
+		})
+	}
+}
+
+type SettlePairInput struct {
+	Currency string `json:"currency"`
+	// Amount — сумма перевода, которую видел пользователь; если долги с тех пор
+	// изменились, взаимозачёт не проводится.
+	Amount *money.Money `json:"amount"`
+}
+
+// SettlePair проводит взаимозачёт между вызывающим и :user_id в одной валюте:
+// фиксирует один перевод на сумму чистого долга и создаёт в каждом событии
+// платёж, закрывающий долг пары, после чего пересчитывает долги этих событий.
+// Валюту можно не указывать, если общие долги только в одной валюте.
+func SettlePair(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("user_id")
+		otherID := common.ParseUintParam(c.Param("user_id"))
+		if otherID == 0 || otherID == userID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Нужен другой пользователь"})
+			return
+		}
+
+		var input SettlePairInput
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&input); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		var settlement models.Settlement
+		err := db.Transaction(func(tx *gorm.DB) error {
+			debts, err := pairDebts(tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "debts"}}),
+				userID, otherID, input.Currency)
+			if err != nil {
+				return err
+			}
+			debts = settleable(debts)
+			nets := netPair(debts, userID, otherID)
+			switch {
+			case len(nets) == 0:
+				return errNothingToSettle
+			case len(nets) > 1:
+				return errManyCurrencies
+			}
+
+			net := nets[0]
+
+			// Пока прошлый взаимозачёт в этой валюте ждёт подтверждения, долги пары
+			// ещё открыты, и второй взаимозачёт закрыл бы их повторно. Оспоренный
+			// взаимозачёт долги не закрыл, поэтому новому не мешает.
+			var pending int64
+			if err := tx.Model(&models.Payment{}).
+				Joins("JOIN settlements ON settlements.id = payments.settlement_id").
+				Where("payments.status = ? AND settlements.currency = ?", models.PaymentPending, net.Currency).
+				Where("(settlements.from_user = ? AND settlements.to_user = ?) OR (settlements.from_user = ? AND settlements.to_user = ?)",
+					userID, otherID, otherID, userID).
+				Count(&pending).Error; err != nil {
+				return err
+			}
+			if pending > 0 {
+				return errPendingSettlement
+			}
+
+			settlement = models.Settlement{
+				Currency:  net.Currency,
+				CreatedBy: userID,
+			}
+			if net.Transfer != nil {
+				settlement.FromUser, settlement.ToUser, settlement.Amount = net.Transfer.FromUser, net.Transfer.ToUser, net.Transfer.Amount
+			} else {
+				// Долги взаимно погашаются полностью — перевод нулевой.
+				settlement.FromUser, settlement.ToUser = userID, otherID
+			}
+			if input.Amount != nil && *input.Amount != settlement.Amount {
+				return fmt.Errorf("%w: сумма взаимозачёта теперь %s", errStaleNetting, settlement.Amount.Format(money.Currency(settlement.Currency)))
+			}
+			if err := tx.Create(&settlement).Error; err != nil {
+				return err
+			}
+
+			// Взаимозачёт проводит получатель перевода или перевод нулевой — подтверждать нечего.
+			status := models.PaymentPending
+			if settlement.ToUser == userID || settlement.Amount == 0 {
+				status = models.PaymentConfirmed
+			}
+			events := make(map[uint]bool)
+			for _, payment := range settlementPayments(settlement, debts, status, time.Now()) {
+				if err := tx.Create(&payment).Error; err != nil {
+					return err
+				}
+				if err := recordPaymentStatus(tx, &payment, userID, ""); err != nil {
+					return err
+				}
+				settlement.Payments = append(settlement.Payments, payment)
+				events[payment.EventID] = true
+			}
+			for eventID := range events {
+				if err := ledger.Recompute(tx, eventID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		switch {
+		case errors.Is(err, errNothingToSettle):
+			c.JSON(http.StatusConflict, gin.H{"error": "Открытых долгов между вами нет"})
+		case errors.Is(err, errManyCurrencies):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Долги в нескольких валютах, укажите currency"})
+		case errors.Is(err, errPendingSettlement):
+			c.JSON(http.StatusConflict, gin.H{"error": "Предыдущий взаимозачёт ещё не подтверждён"})
+		case errors.Is(err, errStaleNetting):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при взаимозачёте"})
+		default:
+			c.JSON(http.StatusCreated, settlement)
+		}
+	}
+}
+
+// pairDebts возвращает открытые долги между userID и otherID во всех событиях,
+// при непустом currency — только в событиях с этой валютой.
+func pairDebts(db *gorm.DB, userID, otherID uint, currency string) ([]PairDebt, error) {
+	query := db.Table("debts").
+		Select("debts.id AS debt_id, debts.event_id, events.name AS event_name, events.currency, "+
+			"debts.from_user, debts.to_user, debts.amount, event_participants.role").
+		Joins("JOIN events ON events.id = debts.event_id").
+		Joins("LEFT JOIN event_participants ON event_participants.event_id = debts.event_id AND event_participants.user_id = ?", userID).
+		Where("debts.is_settled = false AND debts.amount > 0").
+		Where("(debts.from_user = ? AND debts.to_user = ?) OR (debts.from_user = ? AND debts.to_user = ?)",
+			userID, otherID, otherID, userID)
+	if currency != "" {
+		query = query.Where("events.currency = ?", currency)
+	}
+
+	var debts []PairDebt
+	if err := query.Order("debts.event_id, debts.id").Scan(&debts).Error; err != nil {
+		return nil, err
+	}
+	return debts, nil
+}
+
+// settleable оставляет долги из событий, где вызывающий может записывать
+// платежи (роль не ниже member, как у POST /payments).
+func settleable(debts []PairDebt) []PairDebt {
+	kept := make([]PairDebt, 0, len(debts))
+	for _, d := range debts {
+		if (models.EventParticipant{Role: d.Role}).HasRole(models.RoleMember) {
+			kept = append(kept, d)
+		}
+	}
+	return kept
+}
+
+// netPair сводит долги пары по валютам с точки зрения userID.
+func netPair(debts []PairDebt, userID, otherID uint) []PairNet {
+	byCurrency := make(map[string]*PairNet)
+	for _, d := range debts {
+		if byCurrency[d.Currency] == nil {
+			byCurrency[d.Currency] = &PairNet{BalanceTotals: BalanceTotals{Currency: d.Currency}}
+		}
+		amount := d.Amount
+		if d.FromUser == userID {
+			amount = -amount
+		}
+		byCurrency[d.Currency].add(amount)
+	}
+
+	nets := make([]PairNet, 0, len(byCurrency))
+	for _, n := range byCurrency {
+		switch {
+		case n.Net > 0:
+			n.Transfer = &ledger.Transfer{FromUser: otherID, ToUser: userID, Amount: n.Net}
+		case n.Net < 0:
+			n.Transfer = &ledger.Transfer{FromUser: userID, ToUser: otherID, Amount: -n.Net}
+		}
+		nets = append(nets, *n)
+	}
+	sort.Slice(nets, func(i, j int) bool { return nets[i].Currency < nets[j].Currency })
+	return nets
+}
+
+// settlementPayments раскладывает взаимозачёт на платежи по событиям: каждый
+// долг пары закрывается платежом на всю его сумму в ту же сторону. Сумма
+// платежей со знаком совпадает с переводом взаимозачёта.
+func settlementPayments(settlement models.Settlement, debts []PairDebt, status string, at time.Time) []models.Payment {
+	payments := make([]models.Payment, 0, len(debts))
+	for _, d := range debts {
+		payments = append(payments, models.Payment{
+			EventID:      d.EventID,
+			FromUser:     d.FromUser,
+			ToUser:       d.ToUser,
+			Amount:       d.Amount,
+			PaidAt:       at,
+			Status:       status,
+			SettlementID: &settlement.ID,
+		})
+	}
+	return payments
+}
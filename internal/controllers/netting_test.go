@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"split-the-bill/internal/ledger"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+	"testing"
+	"time"
+)
+
+func TestNetPair(t *testing.T) {
+	const me, other = 1, 2
+	tests := []struct {
+		name  string
+		debts []PairDebt
+		want  []PairNet
+	}{
+		{
+			name:  "no debts",
+			debts: nil,
+			want:  []PairNet{},
+		},
+		{
+			name: "other owes me across events",
+			debts: []PairDebt{
+				{EventID: 10, Currency: "RUB", FromUser: other, ToUser: me, Amount: 30000},
+				{EventID: 11, Currency: "RUB", FromUser: other, ToUser: me, Amount: 20000},
+			},
+			want: []PairNet{{
+				BalanceTotals: BalanceTotals{Currency: "RUB", Owed: 50000, Net: 50000},
+				Transfer:      &ledger.Transfer{FromUser: other, ToUser: me, Amount: 50000},
+			}},
+		},
+		{
+			name: "opposite debts net to one transfer",
+			debts: []PairDebt{
+				{EventID: 10, Currency: "RUB", FromUser: other, ToUser: me, Amount: 30000},
+				{EventID: 11, Currency: "RUB", FromUser: me, ToUser: other, Amount: 45000},
+			},
+			want: []PairNet{{
+				BalanceTotals: BalanceTotals{Currency: "RUB", Owe: 45000, Owed: 30000, Net: -15000},
+				Transfer:      &ledger.Transfer{FromUser: me, ToUser: other, Amount: 15000},
+			}},
+		},
+		{
+			name: "debts cancel out",
+			debts: []PairDebt{
+				{EventID: 10, Currency: "EUR", FromUser: other, ToUser: me, Amount: 1000},
+				{EventID: 11, Currency: "EUR", FromUser: me, ToUser: other, Amount: 1000},
+			},
+			want: []PairNet{{
+				BalanceTotals: BalanceTotals{Currency: "EUR", Owe: 1000, Owed: 1000},
+			}},
+		},
+		{
+			name: "currencies are not mixed",
+			debts: []PairDebt{
+				{EventID: 10, Currency: "RUB", FromUser: other, ToUser: me, Amount: 30000},
+				{EventID: 11, Currency: "EUR", FromUser: me, ToUser: other, Amount: 2500},
+				{EventID: 12, Currency: "RUB", FromUser: me, ToUser: other, Amount: 10000},
+			},
+			want: []PairNet{
+				{
+					BalanceTotals: BalanceTotals{Currency: "EUR", Owe: 2500, Net: -2500},
+					Transfer:      &ledger.Transfer{FromUser: me, ToUser: other, Amount: 2500},
+				},
+				{
+					BalanceTotals: BalanceTotals{Currency: "RUB", Owe: 10000, Owed: 30000, Net: 20000},
+					Transfer:      &ledger.Transfer{FromUser: other, ToUser: me, Amount: 20000},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := netPair(tt.debts, me, other); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("netPair() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// С какой стороны ни смотреть, перевод один и тот же.
+func TestNetPairSymmetric(t *testing.T) {
+	debts := []PairDebt{
+		{EventID: 10, Currency: "RUB", FromUser: 2, ToUser: 1, Amount: 30000},
+		{EventID: 11, Currency: "RUB", FromUser: 1, ToUser: 2, Amount: 45000},
+	}
+	mine, theirs := netPair(debts, 1, 2), netPair(debts, 2, 1)
+	if !reflect.DeepEqual(mine[0].Transfer, theirs[0].Transfer) {
+		t.Errorf("transfer %+v from one side, %+v from the other", mine[0].Transfer, theirs[0].Transfer)
+	}
+	if mine[0].Net != -theirs[0].Net {
+		t.Errorf("net %s and %s are not opposite", mine[0].Net, theirs[0].Net)
+	}
+}
+
+func TestSettlementPayments(t *testing.T) {
+	debts := []PairDebt{
+		{DebtID: 1, EventID: 10, Currency: "RUB", FromUser: 2, ToUser: 1, Amount: 30000},
+		{DebtID: 2, EventID: 11, Currency: "RUB", FromUser: 1, ToUser: 2, Amount: 45000},
+		{DebtID: 3, EventID: 12, Currency: "RUB", FromUser: 2, ToUser: 1, Amount: 5000},
+	}
+	net := netPair(debts, 1, 2)[0]
+	settlement := models.Settlement{
+		ID:       7,
+		FromUser: net.Transfer.FromUser,
+		ToUser:   net.Transfer.ToUser,
+		Amount:   net.Transfer.Amount,
+		Currency: net.Currency,
+	}
+	at := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+
+	payments := settlementPayments(settlement, debts, models.PaymentPending, at)
+	if len(payments) != len(debts) {
+		t.Fatalf("got %d payments, want %d", len(payments), len(debts))
+	}
+
+	// Платёж from -> to гасит долг from -> to; в сумме платежи двигают
+	// от отправителя взаимозачёта к получателю ровно его сумму.
+	var moved money.Money
+	for i, p := range payments {
+		d := debts[i]
+		if p.EventID != d.EventID || p.FromUser != d.FromUser || p.ToUser != d.ToUser || p.Amount != d.Amount {
+			t.Errorf("payment %d = %+v, want to close debt %+v", i, p, d)
+		}
+		if p.Status != models.PaymentPending || !p.PaidAt.Equal(at) {
+			t.Errorf("payment %d: status %q at %v", i, p.Status, p.PaidAt)
+		}
+		if p.SettlementID == nil || *p.SettlementID != settlement.ID {
+			t.Errorf("payment %d: settlement %v, want %d", i, p.SettlementID, settlement.ID)
+		}
+		if p.FromUser == settlement.FromUser {
+			moved += p.Amount
+		} else {
+			moved -= p.Amount
+		}
+	}
+	if moved != settlement.Amount {
+		t.Errorf("payments move %s, settlement is %s", moved, settlement.Amount)
+	}
+}
+
+// Зритель не может записывать платежи в событии, поэтому и взаимозачёт
+// не должен закрывать его долги там.
+func TestSettlePairSkipsViewerEvents(t *testing.T) {
+	db := newTestDB(t)
+	alice := createUser(t, db, "Алиса")
+	boris := createUser(t, db, "Борис")
+	trip := createEvent(t, db, alice.ID, map[uint]string{boris.ID: models.RoleMember})
+	flat := createEvent(t, db, boris.ID, map[uint]string{alice.ID: models.RoleViewer})
+
+	r, ev := testRouter(db)
+	ev.POST("/import", ImportExpenses(db))
+	r.GET("/me/balances/:user_id", GetPairBalance(db))
+	r.POST("/me/balances/:user_id/settle", SettlePair(db))
+
+	// В поездке Алиса должна Борису 300, в квартире Борис должен Алисе 500.
+	imports := []struct {
+		event  models.Event
+		userID uint
+		csv    string
+	}{
+		{trip, alice.ID, "date,title,amount,paid_by\n2026-10-17,Ужин,600,Борис\n"},
+		{flat, boris.ID, "date,title,amount,paid_by\n2026-10-17,Аренда,1000,Алиса\n"},
+	}
+	for _, imp := range imports {
+		path := fmt.Sprintf("/events/%d/import", imp.event.ID)
+		if w := request(t, r, http.MethodPost, path, imp.userID, "text/csv", imp.csv); w.Code != http.StatusCreated {
+			t.Fatalf("import into %d: %d %s", imp.event.ID, w.Code, w.Body)
+		}
+	}
+
+	balance := request(t, r, http.MethodGet, fmt.Sprintf("/me/balances/%d", boris.ID), alice.ID, "", "")
+	var shown struct {
+		Net []PairNet `json:"net"`
+	}
+	if err := json.Unmarshal(balance.Body.Bytes(), &shown); err != nil {
+		t.Fatal(err)
+	}
+	want := &ledger.Transfer{FromUser: alice.ID, ToUser: boris.ID, Amount: 30000}
+	if len(shown.Net) != 1 || !reflect.DeepEqual(shown.Net[0].Transfer, want) {
+		t.Errorf("pair balance net = %+v, want transfer %+v", shown.Net, want)
+	}
+
+	w := request(t, r, http.MethodPost, fmt.Sprintf("/me/balances/%d/settle", boris.ID), alice.ID, "", "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("settle: %d %s", w.Code, w.Body)
+	}
+	var settlement models.Settlement
+	if err := json.Unmarshal(w.Body.Bytes(), &settlement); err != nil {
+		t.Fatal(err)
+	}
+	if settlement.FromUser != alice.ID || settlement.ToUser != boris.ID || settlement.Amount != 30000 {
+		t.Errorf("settlement %d -> %d %s, want %d -> %d 300.00",
+			settlement.FromUser, settlement.ToUser, settlement.Amount, alice.ID, boris.ID)
+	}
+	for _, p := range settlement.Payments {
+		if p.EventID != trip.ID {
+			t.Errorf("settlement paid in event %d where the caller is a viewer", p.EventID)
+		}
+	}
+	if got := openDebts(t, db, flat.ID); got[[2]uint{boris.ID, alice.ID}] != 50000 {
+		t.Errorf("debts in viewer event = %v, want Борис -> Алиса 500.00 untouched", got)
+	}
+}
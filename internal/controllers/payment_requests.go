@@ -0,0 +1,230 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"split-the-bill/internal/common"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+	"split-the-bill/internal/payreq"
+	"strconv"
+	"strings"
+)
+
+// Размер PNG с QR-кодом по умолчанию и пределы для ?size=.
+const (
+	defaultQRSize = 512
+	minQRSize     = 128
+	maxQRSize     = 2048
+)
+
+type PaymentDetailsInput struct {
+	Name        string `json:"name" binding:"required"`
+	BankName    string `json:"bank_name"`
+	BIC         string `json:"bic"`
+	Account     string `json:"account"`
+	CorrAccount string `json:"corr_account"`
+	INN         string `json:"inn"`
+	IBAN        string `json:"iban"`
+	SWIFT       string `json:"swift"`
+}
+
+// GetPaymentDetails возвращает реквизиты вызывающего пользователя.
+func GetPaymentDetails(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var details models.UserPaymentDetails
+		err := db.Where("user_id = ?", c.GetUint("user_id")).First(&details).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Реквизиты не указаны"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при загрузке реквизитов"})
+			return
+		}
+		c.JSON(http.StatusOK, details)
+	}
+}
+
+// UpdatePaymentDetails сохраняет реквизиты вызывающего пользователя целиком.
+// Можно указать только российские реквизиты, только IBAN или и те и другие.
+func UpdatePaymentDetails(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input PaymentDetailsInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		details := models.UserPaymentDetails{
+			UserID:      c.GetUint("user_id"),
+			Name:        strings.TrimSpace(input.Name),
+			BankName:    strings.TrimSpace(input.BankName),
+			BIC:         strings.TrimSpace(input.BIC),
+			Account:     strings.TrimSpace(input.Account),
+			CorrAccount: strings.TrimSpace(input.CorrAccount),
+			INN:         strings.TrimSpace(input.INN),
+			IBAN:        strings.ToUpper(strings.ReplaceAll(input.IBAN, " ", "")),
+			SWIFT:       strings.ToUpper(strings.TrimSpace(input.SWIFT)),
+		}
+		if err := payee(details).Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"name", "bank_name", "bic", "account", "corr_account", "inn", "iban", "swift", "updated_at"}),
+		}).Create(&details).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при сохранении реквизитов"})
+			return
+		}
+		c.JSON(http.StatusOK, details)
+	}
+}
+
+// DeletePaymentDetails удаляет реквизиты вызывающего пользователя.
+func DeletePaymentDetails(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := db.Where("user_id = ?", c.GetUint("user_id")).
+			Delete(&models.UserPaymentDetails{}).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при удалении реквизитов"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Реквизиты удалены"})
+	}
+}
+
+// GetPaymentRequest собирает платёжный запрос для открытого долга :debt_id по
+// реквизитам получателя. Формат по умолчанию зависит от валюты события
+// (RUB — ST00012, EUR — EPC QR), ?format= задаёт его явно.
+// ?output=png|svg|text отдаёт QR-код или строку запроса, иначе ответ — JSON.
+// Запрос доступен только должнику и получателю.
+func GetPaymentRequest(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("user_id")
+		var event models.Event
+		if err := db.First(&event, common.ParseUintParam(c.Param("id"))).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Событие не найдено"})
+			return
+		}
+
+		var debt models.Debt
+		err := db.Where("id = ? AND event_id = ?", common.ParseUintParam(c.Param("debt_id")), event.ID).First(&debt).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Долг не найден"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при загрузке долга"})
+			return
+		}
+		if debt.FromUser != userID && debt.ToUser != userID {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Запрос на оплату доступен только участникам долга"})
+			return
+		}
+		if debt.IsSettled || debt.Amount <= 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "Долг уже погашен"})
+			return
+		}
+
+		var details models.UserPaymentDetails
+		err = db.Where("user_id = ?", debt.ToUser).First(&details).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Получатель не указал реквизиты"})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при загрузке реквизитов"})
+			return
+		}
+
+		currency := money.Currency(event.Currency)
+		format := c.Query("format")
+		if format == "" {
+			switch currency {
+			case "RUB":
+				format = payreq.FormatST00012
+			case "EUR":
+				format = payreq.FormatEPC
+			default:
+				c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Для валюты " + event.Currency + " нет формата платёжного запроса"})
+				return
+			}
+		}
+
+		purpose := fmt.Sprintf("Долг по событию «%s»", event.Name)
+		var payload string
+		switch format {
+		case payreq.FormatST00012:
+			if currency != "RUB" {
+				err = fmt.Errorf("%w: ST00012 поддерживает только RUB", payreq.ErrInvalid)
+				break
+			}
+			payload, err = payreq.ST00012(payee(details), debt.Amount, purpose)
+		case payreq.FormatEPC:
+			payload, err = payreq.EPC(payee(details), debt.Amount, currency, purpose)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format: st00012 или epc"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
+
+		switch c.Query("output") {
+		case "", "json":
+			c.JSON(http.StatusOK, gin.H{
+				"format":    format,
+				"payload":   payload,
+				"debt_id":   debt.ID,
+				"from_user": debt.FromUser,
+				"to_user":   debt.ToUser,
+				"amount":    debt.Amount,
+				"currency":  currency,
+				"payee":     details,
+			})
+		case "text":
+			c.String(http.StatusOK, payload)
+		case "png":
+			size := defaultQRSize
+			if v := c.Query("size"); v != "" {
+				size, err = strconv.Atoi(v)
+				if err != nil || size < minQRSize || size > maxQRSize {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("size: от %d до %d", minQRSize, maxQRSize)})
+					return
+				}
+			}
+			png, err := payreq.PNG(payload, size)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании QR-кода"})
+				return
+			}
+			c.Data(http.StatusOK, "image/png", png)
+		case "svg":
+			svg, err := payreq.SVG(payload)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании QR-кода"})
+				return
+			}
+			c.Data(http.StatusOK, "image/svg+xml", svg)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "output: json, text, png или svg"})
+		}
+	}
+}
+
+func payee(d models.UserPaymentDetails) payreq.Payee {
+	return payreq.Payee{
+		Name:        d.Name,
+		BankName:    d.BankName,
+		BIC:         d.BIC,
+		Account:     d.Account,
+		CorrAccount: d.CorrAccount,
+		INN:         d.INN,
+		IBAN:        d.IBAN,
+		SWIFT:       d.SWIFT,
+	}
+}
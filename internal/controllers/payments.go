@@ -0,0 +1,309 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"split-the-bill/internal/common"
+	"split-the-bill/internal/ledger"
+	"split-the-bill/internal/middleware"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+	"time"
+)
+
+var (
+	errPaymentStatus   = errors.New("payment status does not allow this")
+	errAlreadyReversed = errors.New("payment already reversed")
+)
+
+type PaymentStatusInput struct {
+	Comment string `json:"comment"`
+}
+
+// ConfirmPayment подтверждает получение платежа; после этого платёж уменьшает долги.
+// Подтвердить можно ожидающий или ранее оспоренный платёж.
+func ConfirmPayment(db *gorm.DB) gin.HandlerFunc {
+	return changePaymentStatus(db, models.PaymentConfirmed, models.PaymentPending, models.PaymentDisputed)
+}
+
+// DisputePayment отмечает, что получатель денег не получил. Оспоренный платёж
+// на долги не влияет, пока получатель его не подтвердит.
+func DisputePayment(db *gorm.DB) gin.HandlerFunc {
+	return changePaymentStatus(db, models.PaymentDisputed, models.PaymentPending)
+}
+
+// changePaymentStatus переводит платёж :payment_id события в статус status.
+// Менять статус может только получатель, а за гостя — админ события (см.
+// confirmsFor). Платежи взаимозачёта меняют статус все вместе, так как это
+// один перевод, и решает получатель этого перевода.
+func changePaymentStatus(db *gorm.DB, status string, from ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("user_id")
+
+		var input PaymentStatusInput
+		if c.Request.ContentLength > 0 {
+			if err := c.ShouldBindJSON(&input); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+
+		var payment models.Payment
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND event_id = ?", c.Param("payment_id"), common.ParseUintParam(c.Param("id"))).
+				First(&payment).Error; err != nil {
+				return err
+			}
+			payments := []models.Payment{payment}
+			payee := payment.ToUser
+			if payment.SettlementID != nil {
+				var settlement models.Settlement
+				if err := tx.First(&settlement, *payment.SettlementID).Error; err != nil {
+					return err
+				}
+				payee = settlement.ToUser
+			}
+			if ok, err := confirmsFor(tx, payment.EventID, payee, userID); err != nil {
+				return err
+			} else if !ok {
+				return errForbidden
+			}
+			if payment.SettlementID != nil {
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+					Where("settlement_id = ?", *payment.SettlementID).
+					Order("id").Find(&payments).Error; err != nil {
+					return err
+				}
+			}
+
+			for _, p := range payments {
+				if !hasStatus(p.Status, from) {
+					return errPaymentStatus
+				}
+			}
+
+			events := make(map[uint]bool)
+			for i := range payments {
+				payments[i].Status = status
+				if err := tx.Model(&payments[i]).Update("status", status).Error; err != nil {
+					return err
+				}
+				if err := recordPaymentStatus(tx, &payments[i], userID, input.Comment); err != nil {
+					return err
+				}
+				events[payments[i].EventID] = true
+				if payments[i].ID == payment.ID {
+					payment = payments[i]
+				}
+			}
+			for eventID := range events {
+				if err := ledger.Recompute(tx, eventID); err != nil {
+					return err
+				}
+			}
+			return tx.Preload("History", func(db *gorm.DB) *gorm.DB {
+				return db.Order("created_at, id")
+			}).First(&payment, payment.ID).Error
+		})
+
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Платёж не найден"})
+		case errors.Is(err, errForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Статус платежа может менять только получатель, а за гостя — админ события"})
+		case errors.Is(err, errPaymentStatus):
+			c.JSON(http.StatusConflict, gin.H{"error": "Платёж в статусе " + payment.Status + ", так изменить его нельзя"})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при изменении статуса платежа"})
+		default:
+			c.JSON(http.StatusOK, payment)
+		}
+	}
+}
+
+type ReversePaymentInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// ReversePayment отменяет ошибочный подтверждённый платёж. Сам платёж остаётся
+// в истории, а рядом создаётся сторнирующая запись с обратной суммой, после
+// чего долги события пересчитываются. Отменить платёж могут его отправитель,
+// получатель и админы события; платежи взаимозачёта отменяются все вместе
+// и только участниками взаимозачёта.
+func ReversePayment(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetUint("user_id")
+
+		var input ReversePaymentInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Укажите причину отмены"})
+			return
+		}
+
+		var reversals []models.Payment
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var payment models.Payment
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ? AND event_id = ?", c.Param("payment_id"), common.ParseUintParam(c.Param("id"))).
+				First(&payment).Error; err != nil {
+				return err
+			}
+			if payment.ReversalOf != nil {
+				return errPaymentStatus
+			}
+
+			payments := []models.Payment{payment}
+			if payment.SettlementID == nil {
+				participant, _ := middleware.Participant(c)
+				if payment.FromUser != userID && payment.ToUser != userID && !participant.HasRole(models.RoleAdmin) {
+					return errForbidden
+				}
+			} else {
+				// Взаимозачёт затрагивает и другие события, где у админа этого
+				// события прав может не быть, поэтому отменить его может только пара.
+				var settlement models.Settlement
+				if err := tx.First(&settlement, *payment.SettlementID).Error; err != nil {
+					return err
+				}
+				if settlement.FromUser != userID && settlement.ToUser != userID {
+					return errForbidden
+				}
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+					Where("settlement_id = ? AND reversal_of IS NULL", *payment.SettlementID).
+					Order("id").Find(&payments).Error; err != nil {
+					return err
+				}
+			}
+
+			var ids []uint
+			for _, p := range payments {
+				if p.Status != models.PaymentConfirmed {
+					return errPaymentStatus
+				}
+				ids = append(ids, p.ID)
+			}
+			var reversed int64
+			if err := tx.Model(&models.Payment{}).Where("reversal_of IN ?", ids).Count(&reversed).Error; err != nil {
+				return err
+			}
+			if reversed > 0 {
+				return errAlreadyReversed
+			}
+
+			now := time.Now()
+			events := make(map[uint]bool)
+			for _, p := range payments {
+				reversal := models.Payment{
+					EventID:      p.EventID,
+					FromUser:     p.FromUser,
+					ToUser:       p.ToUser,
+					Amount:       -p.Amount,
+					PaidAt:       now,
+					Status:       models.PaymentConfirmed,
+					SettlementID: p.SettlementID,
+					ReversalOf:   &p.ID,
+					Reason:       input.Reason,
+				}
+				if err := tx.Create(&reversal).Error; err != nil {
+					return err
+				}
+				if err := recordPaymentStatus(tx, &reversal, userID, input.Reason); err != nil {
+					return err
+				}
+				reversals = append(reversals, reversal)
+				events[p.EventID] = true
+			}
+			for eventID := range events {
+				if err := ledger.Recompute(tx, eventID); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Платёж не найден"})
+		case errors.Is(err, errForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Нет прав на отмену этого платежа"})
+		case errors.Is(err, errPaymentStatus):
+			c.JSON(http.StatusConflict, gin.H{"error": "Отменить можно только подтверждённый платёж"})
+		case errors.Is(err, errAlreadyReversed), isDuplicateKey(db, err):
+			c.JSON(http.StatusConflict, gin.H{"error": "Платёж уже отменён"})
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при отмене платежа"})
+		default:
+			c.JSON(http.StatusCreated, reversals)
+		}
+	}
+}
+
+// PaymentApplication объясняет, как платёж лёг на долги: ToDebt гасит открытый
+// долг плательщика перед получателем, Credit — переплата, которая остаётся за
+// получателем как встречный долг. Debts — открытые долги пары после платежа.
+// У неподтверждённого платежа Projected = true: ToDebt и Credit — только
+// прогноз, а Debts — текущие долги, платёж в них ещё не учтён.
+type PaymentApplication struct {
+	Outstanding money.Money   `json:"outstanding"`
+	ToDebt      money.Money   `json:"to_debt"`
+	Credit      money.Money   `json:"credit"`
+	Projected   bool          `json:"projected"`
+	Debts       []models.Debt `json:"debts"`
+}
+
+func applyPayment(amount, outstanding money.Money) PaymentApplication {
+	applied := PaymentApplication{Outstanding: outstanding, ToDebt: amount, Debts: []models.Debt{}}
+	if amount > outstanding {
+		applied.ToDebt, applied.Credit = outstanding, amount-outstanding
+	}
+	return applied
+}
+
+// confirmsFor сообщает, может ли userID подтверждать платежи получателю payee
+// в событии eventID. Обычно это только сам получатель, но гость войти не
+// может, поэтому за гостя события платежи подтверждают его админы.
+func confirmsFor(db *gorm.DB, eventID, payee, userID uint) (bool, error) {
+	if payee == userID {
+		return true, nil
+	}
+	var guests int64
+	if err := db.Model(&models.User{}).
+		Where("id = ? AND guest_event_id = ?", payee, eventID).
+		Count(&guests).Error; err != nil || guests == 0 {
+		return false, err
+	}
+	var participant models.EventParticipant
+	err := db.Where("event_id = ? AND user_id = ?", eventID, userID).First(&participant).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil && participant.HasRole(models.RoleAdmin), err
+}
+
+// recordPaymentStatus добавляет текущий статус платежа в его историю.
+func recordPaymentStatus(tx *gorm.DB, payment *models.Payment, userID uint, comment string) error {
+	change := models.PaymentStatusChange{
+		PaymentID: payment.ID,
+		Status:    payment.Status,
+		ChangedBy: userID,
+		Comment:   comment,
+	}
+	if err := tx.Create(&change).Error; err != nil {
+		return err
+	}
+	payment.History = append(payment.History, change)
+	return nil
+}
+
+func hasStatus(status string, statuses []string) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
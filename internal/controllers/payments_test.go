@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"reflect"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+	"testing"
+)
+
+// Гость войти не может, поэтому платёж гостю подтверждает админ события,
+// а обычный участник — нет.
+func TestGuestPaymentConfirmedByAdmin(t *testing.T) {
+	db := newTestDB(t)
+	alice := createUser(t, db, "Алиса")
+	boris := createUser(t, db, "Борис")
+	event := createEvent(t, db, alice.ID, map[uint]string{boris.ID: models.RoleMember})
+	guest, err := createGuest(db, event.ID, "Гость")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, ev := testRouter(db)
+	ev.POST("/import", ImportExpenses(db))
+	ev.POST("/payments", func(c *gin.Context) { AddPayment(c, db) })
+	ev.POST("/payments/:payment_id/confirm", ConfirmPayment(db))
+	ev.POST("/payments/:payment_id/dispute", DisputePayment(db))
+
+	// Гость заплатил 900 за троих: Алиса и Борис должны ему по 300.
+	csv := "date,title,amount,paid_by\n2026-10-17,Ужин,900,Гость\n"
+	if w := request(t, r, http.MethodPost, "/events/1/import", alice.ID, "text/csv", csv); w.Code != http.StatusCreated {
+		t.Fatalf("import: %d %s", w.Code, w.Body)
+	}
+
+	addPayment := func(userID uint, body string) models.Payment {
+		t.Helper()
+		w := request(t, r, http.MethodPost, "/events/1/payments", userID, "application/json", body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("add payment: %d %s", w.Code, w.Body)
+		}
+		var resp struct {
+			Payment models.Payment `json:"payment"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Payment
+	}
+
+	fromBoris := addPayment(boris.ID, fmt.Sprintf(`{"to_user": %d, "amount": "300.00"}`, guest.ID))
+	if fromBoris.Status != models.PaymentPending {
+		t.Fatalf("payment from member to guest is %q, want pending", fromBoris.Status)
+	}
+	confirm := fmt.Sprintf("/events/1/payments/%d/confirm", fromBoris.ID)
+	if w := request(t, r, http.MethodPost, confirm, boris.ID, "", ""); w.Code != http.StatusForbidden {
+		t.Errorf("payer confirmed own payment to guest: %d %s", w.Code, w.Body)
+	}
+	if w := request(t, r, http.MethodPost, confirm, alice.ID, "", ""); w.Code != http.StatusOK {
+		t.Fatalf("admin confirm: %d %s", w.Code, w.Body)
+	}
+
+	want := map[[2]uint]money.Money{{alice.ID, guest.ID}: 30000}
+	if got := openDebts(t, db, event.ID); !reflect.DeepEqual(got, want) {
+		t.Errorf("debts after confirmation = %v, want %v", got, want)
+	}
+
+	// Платёж гостю, который записывает сам админ, засчитывается сразу.
+	fromAlice := addPayment(alice.ID, fmt.Sprintf(`{"to_user": %d, "amount": "300.00"}`, guest.ID))
+	if fromAlice.Status != models.PaymentConfirmed || fromAlice.FromUser != alice.ID {
+		t.Errorf("payment recorded by admin = %+v, want confirmed from admin", fromAlice)
+	}
+	if got := openDebts(t, db, event.ID); len(got) != 0 {
+		t.Errorf("debts after admin payment = %v, want none", got)
+	}
+
+	// Оспорить платёж гостю участник тоже не может.
+	disputed := addPayment(boris.ID, fmt.Sprintf(`{"to_user": %d, "amount": "1.00"}`, guest.ID))
+	dispute := fmt.Sprintf("/events/1/payments/%d/dispute", disputed.ID)
+	if w := request(t, r, http.MethodPost, dispute, boris.ID, "", ""); w.Code != http.StatusForbidden {
+		t.Errorf("member disputed payment to guest: %d %s", w.Code, w.Body)
+	}
+	if w := request(t, r, http.MethodPost, dispute, alice.ID, "", ""); w.Code != http.StatusOK {
+		t.Errorf("admin dispute: %d %s", w.Code, w.Body)
+	}
+}
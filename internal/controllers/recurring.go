@@ -0,0 +1,187 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"split-the-bill/internal/common"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/recurring"
+	"split-the-bill/internal/rrule"
+	"split-the-bill/internal/split"
+	"time"
+)
+
+type RecurringExpenseInput struct {
+	CreateExpenseInput
+	Schedule string     `json:"schedule" binding:"required"`
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+}
+
+func CreateRecurringExpense(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		eventID := common.ParseUintParam(c.Param("id"))
+
+		var input RecurringExpenseInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if input.Amount <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Сумма траты должна быть положительной"})
+			return
+		}
+		if len(input.Items) > 0 || input.SplitMode == split.ModeItemized {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Регулярная трата не может делиться по позициям чека"})
+			return
+		}
+		if len(input.Adjustments) > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Регулярная трата не поддерживает налоги, чаевые и скидки"})
+			return
+		}
+		rule, err := rrule.Parse(input.Schedule)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		rec := models.RecurringExpense{
+			EventID:   eventID,
+			Title:     input.Title,
+			Amount:    input.Amount,
+			PaidBy:    input.PaidBy,
+			Schedule:  input.Schedule,
+			StartsAt:  time.Now(),
+			EndsAt:    input.EndsAt,
+			Status:    models.RecurringActive,
+			CreatedBy: c.GetUint("user_id"),
+		}
+		if input.StartsAt != nil {
+			rec.StartsAt = *input.StartsAt
+		}
+
+		next, ok := rule.Next(rec.StartsAt, rec.StartsAt.Add(-time.Nanosecond))
+		if !ok || (rec.EndsAt != nil && next.After(*rec.EndsAt)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Расписание не даёт ни одного повторения"})
+			return
+		}
+		rec.NextRunAt = &next
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// Проверяем деление заранее, чтобы шаблон не падал при каждом запуске.
+			mode, _, err := buildShares(tx, eventID, input.CreateExpenseInput, split.Remainder{Payer: input.PaidBy})
+			if err != nil {
+				return err
+			}
+			rec.SplitMode = string(mode)
+
+			for _, s := range input.Shares {
+				rec.Shares = append(rec.Shares, models.RecurringExpenseShare{
+					UserID:      s.UserID,
+					ShareAmount: s.ShareAmount,
+					Percent:     s.Percent,
+					Weight:      s.Weight,
+				})
+			}
+			return tx.Create(&rec).Error
+		})
+		if errors.Is(err, split.ErrInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при создании регулярной траты"})
+			return
+		}
+
+		c.JSON(http.StatusCreated, rec)
+	}
+}
+
+func ListRecurringExpenses(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var recs []models.RecurringExpense
+		db.Preload("Shares").Where("event_id = ?", c.Param("id")).Order("id").Find(&recs)
+		c.JSON(http.StatusOK, recs)
+	}
+}
+
+// RecurringAction — операция над шаблоном: pause, resume, skip или end.
+func RecurringAction(db *gorm.DB, action string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var rec models.RecurringExpense
+		if err := db.Where("id = ? AND event_id = ?", c.Param("recurring_id"), c.Param("id")).
+			First(&rec).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Регулярная трата не найдена"})
+			return
+		}
+		if !canEditOwn(c, rec.CreatedBy, rec.PaidBy) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Участник может управлять только своими регулярными тратами"})
+			return
+		}
+		if rec.Status == models.RecurringEnded {
+			c.JSON(http.StatusConflict, gin.H{"error": "Регулярная трата уже завершена"})
+			return
+		}
+
+		rule, err := rrule.Parse(rec.Schedule)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		switch action {
+		case "pause":
+			rec.Status = models.RecurringPaused
+		case "resume":
+			// Повторения, пропущенные на паузе, не создаются задним числом.
+			rec.Status = models.RecurringActive
+			if rec.NextRunAt != nil && rec.NextRunAt.Before(time.Now()) {
+				recurring.Advance(&rec, rule, time.Now())
+			}
+		case "skip":
+			if rec.NextRunAt != nil {
+				recurring.Advance(&rec, rule, *rec.NextRunAt)
+			}
+		case "end":
+			rec.Status = models.RecurringEnded
+			rec.NextRunAt = nil
+		}
+
+		if err := db.Omit("Shares").Save(&rec).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Ошибка при обновлении регулярной траты"})
+			return
+		}
+		c.JSON(http.StatusOK, rec)
+	}
+}
+
+// CreateOccurrence создаёт трату-повторение шаблона rec через тот же путь,
+// что и AddExpense. Подходит как recurring.CreateFunc.
+func CreateOccurrence(tx *gorm.DB, event models.Event, rec models.RecurringExpense, occurrence time.Time) error {
+	paidAt := occurrence
+	input := CreateExpenseInput{
+		Title:     rec.Title,
+		Amount:    rec.Amount,
+		PaidBy:    rec.PaidBy,
+		PaidAt:    &paidAt,
+		SplitMode: split.Mode(rec.SplitMode),
+	}
+	for _, s := range rec.Shares {
+		input.Shares = append(input.Shares, ShareInput{
+			UserID:      s.UserID,
+			ShareAmount: s.ShareAmount,
+			Percent:     s.Percent,
+			Weight:      s.Weight,
+		})
+	}
+
+	expense := models.Expense{
+		CreatedBy:          rec.CreatedBy,
+		RecurringExpenseID: &rec.ID,
+		OccurrenceAt:       &occurrence,
+	}
+	return CreateExpense(tx, event, &expense, input)
+}
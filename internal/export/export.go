@@ -0,0 +1,94 @@
+// Package export записывает таблицы события в CSV и XLSX.
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"io"
+	"split-the-bill/internal/money"
+	"time"
+)
+
+type cellKind int
+
+const (
+	kindText cellKind = iota
+	kindDate
+	kindMoney
+)
+
+// Cell — ячейка таблицы: текст, дата или сумма. Суммы в CSV записываются
+// строкой в валюте события, а в XLSX — числом с денежным форматом.
+type Cell struct {
+	kind  cellKind
+	text  string
+	time  time.Time
+	money money.Money
+}
+
+func Text(s string) Cell {
+	return Cell{kind: kindText, text: s}
+}
+
+func Date(t time.Time) Cell {
+	return Cell{kind: kindDate, time: t}
+}
+
+func Amount(m money.Money) Cell {
+	return Cell{kind: kindMoney, money: m}
+}
+
+func (c Cell) empty() bool {
+	return c.kind == kindText && c.text == ""
+}
+
+func (c Cell) format(cur money.Currency) string {
+	switch c.kind {
+	case kindDate:
+		return c.time.Format("2006-01-02 15:04")
+	case kindMoney:
+		return c.money.Format(cur)
+	}
+	return c.text
+}
+
+// Sheet — лист таблицы; в CSV каждый лист становится отдельным файлом.
+type Sheet struct {
+	Name   string
+	Header []string
+	Rows   [][]Cell
+}
+
+// WriteCSV записывает один лист в CSV.
+func WriteCSV(w io.Writer, sheet Sheet, cur money.Currency) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(sheet.Header); err != nil {
+		return err
+	}
+	for _, row := range sheet.Rows {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			record[i] = cell.format(cur)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteZip записывает листы ZIP-архивом из CSV-файлов <имя листа>.csv.
+func WriteZip(w io.Writer, sheets []Sheet, cur money.Currency) error {
+	zw := zip.NewWriter(w)
+	for _, sheet := range sheets {
+		f, err := zw.Create(sheet.Name + ".csv")
+		if err != nil {
+			return err
+		}
+		if err := WriteCSV(f, sheet, cur); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
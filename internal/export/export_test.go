@@ -0,0 +1,134 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testSheets() []Sheet {
+	paidAt := time.Date(2026, time.October, 17, 19, 30, 0, 0, time.UTC)
+	return []Sheet{
+		{
+			Name:   "expenses",
+			Header: []string{"date", "title", "amount", "paid_by", "Алиса", "Борис"},
+			Rows: [][]Cell{
+				{Date(paidAt), Text("Ужин, бар"), Amount(245050), Text("Алиса"), Amount(122525), Amount(122525)},
+				{Date(paidAt.AddDate(0, 0, 1)), Text("Такси"), Amount(60000), Text("Борис"), Text(""), Amount(60000)},
+			},
+		},
+		{
+			Name:   "balances",
+			Header: []string{"user", "net"},
+			Rows: [][]Cell{
+				{Text("Алиса"), Amount(122525)},
+				{Text("Борис"), Amount(-122525)},
+			},
+		},
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, testSheets()[0], "RUB"); err != nil {
+		t.Fatal(err)
+	}
+	want := "date,title,amount,paid_by,Алиса,Борис\n" +
+		"2026-10-17 19:30,\"Ужин, бар\",2\u00a0450.50\u00a0₽,Алиса,1\u00a0225.25\u00a0₽,1\u00a0225.25\u00a0₽\n" +
+		"2026-10-18 19:30,Такси,600.00\u00a0₽,Борис,,600.00\u00a0₽\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteCSV() =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteZip(&buf, testSheets(), "EUR"); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(data)
+	}
+	if want := []string{"expenses.csv", "balances.csv"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("zip files = %v, want %v", names, want)
+	}
+	if want := "user,net\nАлиса,1\u00a0225.25\u00a0€\nБорис,-1\u00a0225.25\u00a0€\n"; files["balances.csv"] != want {
+		t.Errorf("balances.csv = %q, want %q", files["balances.csv"], want)
+	}
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, testSheets(), "RUB"); err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if got, want := f.GetSheetList(), []string{"expenses", "balances"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("sheets = %v, want %v", got, want)
+	}
+
+	raw := excelize.Options{RawCellValue: true}
+	tests := []struct {
+		sheet, cell, want string
+	}{
+		{"expenses", "A1", "date"},
+		{"expenses", "E1", "Алиса"},
+		{"expenses", "B2", "Ужин, бар"},
+		// Суммы — числа в единицах валюты, а не отформатированные строки.
+		{"expenses", "C2", "2450.5"},
+		{"expenses", "F3", "600"},
+		{"expenses", "E3", ""},
+		{"balances", "B3", "-1225.25"},
+	}
+	for _, tt := range tests {
+		got, err := f.GetCellValue(tt.sheet, tt.cell, raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s!%s = %q, want %q", tt.sheet, tt.cell, got, tt.want)
+		}
+	}
+
+	// Дата записана датой, а не текстом.
+	cellType, err := f.GetCellType("expenses", "A2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cellType == excelize.CellTypeInlineString || cellType == excelize.CellTypeSharedString {
+		t.Errorf("expenses!A2 is a string cell, want a date")
+	}
+	date, err := f.GetCellValue("expenses", "A2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if date != "17.10.2026 19:30" {
+		t.Errorf("expenses!A2 = %q, want %q", date, "17.10.2026 19:30")
+	}
+}
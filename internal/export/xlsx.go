@@ -0,0 +1,94 @@
+package export
+
+import (
+	"github.com/xuri/excelize/v2"
+	"io"
+	"math"
+	"split-the-bill/internal/money"
+	"strings"
+)
+
+// WriteXLSX записывает листы в одну книгу XLSX. Суммы — числа с форматом
+// валюты события, даты — настоящие даты, поэтому по ним можно считать.
+func WriteXLSX(w io.Writer, sheets []Sheet, cur money.Currency) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	moneyFormat := `#,##0.00 "` + strings.ReplaceAll(cur.Symbol(), `"`, `""`) + `"`
+	dateFormat := "dd.mm.yyyy hh:mm"
+	moneyStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &moneyFormat})
+	if err != nil {
+		return err
+	}
+	dateStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return err
+	}
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return err
+	}
+
+	for i, sheet := range sheets {
+		if i == 0 {
+			err = f.SetSheetName("Sheet1", sheet.Name)
+		} else {
+			_, err = f.NewSheet(sheet.Name)
+		}
+		if err != nil {
+			return err
+		}
+
+		for col, title := range sheet.Header {
+			cell, _ := excelize.CoordinatesToCellName(col+1, 1)
+			if err := f.SetCellValue(sheet.Name, cell, title); err != nil {
+				return err
+			}
+			if err := f.SetCellStyle(sheet.Name, cell, cell, headerStyle); err != nil {
+				return err
+			}
+		}
+
+		for r, row := range sheet.Rows {
+			for col, value := range row {
+				if value.empty() {
+					continue
+				}
+				cell, _ := excelize.CoordinatesToCellName(col+1, r+2)
+				switch value.kind {
+				case kindMoney:
+					err = f.SetCellValue(sheet.Name, cell, float64(value.money.Minor())/math.Pow10(money.Scale))
+					if err == nil {
+						err = f.SetCellStyle(sheet.Name, cell, cell, moneyStyle)
+					}
+				case kindDate:
+					err = f.SetCellValue(sheet.Name, cell, value.time)
+					if err == nil {
+						err = f.SetCellStyle(sheet.Name, cell, cell, dateStyle)
+					}
+				default:
+					err = f.SetCellValue(sheet.Name, cell, value.text)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if len(sheet.Header) > 0 {
+			last, _ := excelize.ColumnNumberToName(len(sheet.Header))
+			if err := f.SetColWidth(sheet.Name, "A", last, 16); err != nil {
+				return err
+			}
+		}
+		if err := f.SetPanes(sheet.Name, &excelize.Panes{
+			Freeze:      true,
+			YSplit:      1,
+			TopLeftCell: "A2",
+			ActivePane:  "bottomLeft",
+		}); err != nil {
+			return err
+		}
+	}
+	return f.Write(w)
+}
@@ -0,0 +1,137 @@
+// Package fiscal разбирает строку из QR-кода кассового чека ФНС, например
+//
+//	t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1
+//
+// t — дата и время расчёта, s — сумма, fn — номер фискального накопителя,
+// i — номер фискального документа, fp — фискальный признак, n — признак расчёта.
+package fiscal
+
+import (
+	"errors"
+	"fmt"
+	"split-the-bill/internal/money"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalid = errors.New("некорректный QR-код чека")
+
+// Признаки расчёта (тег 1054).
+const (
+	TypeIncome        = 1 // приход
+	TypeIncomeReturn  = 2 // возврат прихода
+	TypeOutcome       = 3 // расход
+	TypeOutcomeReturn = 4 // возврат расхода
+)
+
+// Location — часовой пояс, в котором трактуется t: в QR-коде время указано
+// без пояса, по местному времени кассы, поэтому берём московское.
+var Location = time.FixedZone("MSK", 3*60*60)
+
+// Receipt — реквизиты чека из QR-кода.
+type Receipt struct {
+	Time time.Time   `json:"time"`
+	Sum  money.Money `json:"sum"`
+	FN   string      `json:"fn"`
+	FD   string      `json:"fd"`
+	FP   string      `json:"fp"`
+	Type int         `json:"type"`
+}
+
+// Ref — ссылка, однозначно определяющая чек: номер накопителя, номер
+// документа и фискальный признак.
+func (r Receipt) Ref() string {
+	return r.FN + "-" + r.FD + "-" + r.FP
+}
+
+// Parse разбирает строку QR-кода. Порядок параметров не важен, неизвестные
+// параметры пропускаются, а повторяющиеся считаются ошибкой.
+func Parse(s string) (Receipt, error) {
+	s = strings.TrimSpace(s)
+	// Некоторые приложения отдают строку вместе с адресом проверки чека.
+	if i := strings.IndexByte(s, '?'); i >= 0 {
+		s = s[i+1:]
+	}
+	if s == "" {
+		return Receipt{}, fmt.Errorf("%w: пустая строка", ErrInvalid)
+	}
+
+	params := make(map[string]string)
+	for _, pair := range strings.Split(s, "&") {
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return Receipt{}, fmt.Errorf("%w: параметр %q без значения", ErrInvalid, pair)
+		}
+		key = strings.ToLower(key)
+		if _, dup := params[key]; dup {
+			return Receipt{}, fmt.Errorf("%w: параметр %s повторяется", ErrInvalid, key)
+		}
+		params[key] = value
+	}
+	for _, key := range []string{"t", "s", "fn", "i", "fp", "n"} {
+		if params[key] == "" {
+			return Receipt{}, fmt.Errorf("%w: нет параметра %s", ErrInvalid, key)
+		}
+	}
+
+	var r Receipt
+	var err error
+	if r.Time, err = parseTime(params["t"]); err != nil {
+		return Receipt{}, err
+	}
+	if r.Sum, err = money.Parse(params["s"]); err != nil || r.Sum <= 0 {
+		return Receipt{}, fmt.Errorf("%w: сумма %q", ErrInvalid, params["s"])
+	}
+	if r.FN, err = digits("fn", params["fn"], 16); err != nil {
+		return Receipt{}, err
+	}
+	if r.FD, err = digits("i", params["i"], 10); err != nil {
+		return Receipt{}, err
+	}
+	if r.FP, err = digits("fp", params["fp"], 10); err != nil {
+		return Receipt{}, err
+	}
+	r.Type, err = strconv.Atoi(params["n"])
+	if err != nil || r.Type < TypeIncome || r.Type > TypeOutcomeReturn {
+		return Receipt{}, fmt.Errorf("%w: признак расчёта %q", ErrInvalid, params["n"])
+	}
+	return r, nil
+}
+
+// parseTime принимает время с секундами и без: 20261017T1930 и 20261017T193005.
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{"20060102T1504", "20060102T150405"} {
+		if len(s) != len(layout) {
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, s, Location); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: время %q", ErrInvalid, s)
+}
+
+// digits проверяет, что значение — строка цифр не длиннее max. Ведущие нули
+// у номера документа и признака в разных QR-кодах то есть, то нет, поэтому
+// они отбрасываются, чтобы Ref был одинаковым; у номера накопителя их нет.
+func digits(key, s string, max int) (string, error) {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("%w: %s %q содержит не только цифры", ErrInvalid, key, s)
+		}
+	}
+	if key != "fn" {
+		s = strings.TrimLeft(s, "0")
+		if s == "" {
+			s = "0"
+		}
+	}
+	if len(s) > max {
+		return "", fmt.Errorf("%w: %s длиннее %d цифр", ErrInvalid, key, max)
+	}
+	return s, nil
+}
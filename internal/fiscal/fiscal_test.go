@@ -0,0 +1,175 @@
+package fiscal
+
+import (
+	"errors"
+	"split-the-bill/internal/money"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	want := Receipt{
+		Time: time.Date(2026, 10, 17, 19, 30, 0, 0, Location),
+		Sum:  money.FromMinor(245000),
+		FN:   "9960440300000000",
+		FD:   "12345",
+		FP:   "1234567890",
+		Type: TypeIncome,
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want Receipt
+	}{
+		{
+			name: "canonical",
+			in:   "t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1",
+			want: want,
+		},
+		{
+			name: "reordered params",
+			in:   "fn=9960440300000000&n=1&fp=1234567890&s=2450.00&i=12345&t=20261017T1930",
+			want: want,
+		},
+		{
+			name: "surrounding whitespace",
+			in:   "  t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1\n",
+			want: want,
+		},
+		{
+			name: "check url prefix",
+			in:   "https://check.ofd.ru/rec?t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1",
+			want: want,
+		},
+		{
+			name: "upper-case keys and unknown params",
+			in:   "T=20261017T1930&S=2450.00&FN=9960440300000000&I=12345&FP=1234567890&N=1&extra=x",
+			want: want,
+		},
+		{
+			name: "integer sum",
+			in:   "t=20261017T1930&s=2450&fn=9960440300000000&i=12345&fp=1234567890&n=1",
+			want: want,
+		},
+		{
+			name: "empty pair",
+			in:   "t=20261017T1930&&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1&",
+			want: want,
+		},
+		{
+			name: "leading zeros in fd and fp",
+			in:   "t=20261017T1930&s=2450.00&fn=9960440300000000&i=0012345&fp=01234567890&n=1",
+			want: func() Receipt {
+				r := want
+				r.FP = "1234567890"
+				return r
+			}(),
+		},
+		{
+			name: "seconds and kopecks",
+			in:   "t=20260101T000005&s=0.01&fn=9960440300000000&i=1&fp=1&n=3",
+			want: Receipt{
+				Time: time.Date(2026, 1, 1, 0, 0, 5, 0, Location),
+				Sum:  money.FromMinor(1),
+				FN:   "9960440300000000",
+				FD:   "1",
+				FP:   "1",
+				Type: TypeOutcome,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.in, err)
+			}
+			if !got.Time.Equal(tt.want.Time) || got.Sum != tt.want.Sum || got.FN != tt.want.FN ||
+				got.FD != tt.want.FD || got.FP != tt.want.FP || got.Type != tt.want.Type {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimeZone(t *testing.T) {
+	r, err := Parse("t=20261017T1930&s=1&fn=1&i=1&fp=1&n=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Time.UTC(); !got.Equal(time.Date(2026, 10, 17, 16, 30, 0, 0, time.UTC)) {
+		t.Errorf("time in UTC = %v, want 16:30", got)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	const valid = "t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1"
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"empty", ""},
+		{"only whitespace", "   "},
+		{"garbage", "hello world"},
+		{"missing t", "s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1"},
+		{"missing s", "t=20261017T1930&fn=9960440300000000&i=12345&fp=1234567890&n=1"},
+		{"missing fn", "t=20261017T1930&s=2450.00&i=12345&fp=1234567890&n=1"},
+		{"missing i", "t=20261017T1930&s=2450.00&fn=9960440300000000&fp=1234567890&n=1"},
+		{"missing fp", "t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&n=1"},
+		{"missing n", "t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890"},
+		{"empty value", "t=&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1"},
+		{"duplicate key", valid + "&s=1.00"},
+		{"duplicate key different case", valid + "&S=1.00"},
+		{"bad time", "t=2026-10-17 19:30&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1"},
+		{"impossible date", "t=20261317T1930&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1"},
+		{"short time", "t=20261017T193&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1"},
+		{"zero sum", "t=20261017T1930&s=0.00&fn=9960440300000000&i=12345&fp=1234567890&n=1"},
+		{"negative sum", "t=20261017T1930&s=-5.00&fn=9960440300000000&i=12345&fp=1234567890&n=1"},
+		{"fractional kopecks", "t=20261017T1930&s=2450.005&fn=9960440300000000&i=12345&fp=1234567890&n=1"},
+		{"comma in sum", "t=20261017T1930&s=2450,00&fn=9960440300000000&i=12345&fp=1234567890&n=1"},
+		{"letters in fn", "t=20261017T1930&s=2450.00&fn=99604403000000AB&i=12345&fp=1234567890&n=1"},
+		{"fn too long", "t=20261017T1930&s=2450.00&fn=99604403000000001&i=12345&fp=1234567890&n=1"},
+		{"fd too long", "t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345678901&fp=1234567890&n=1"},
+		{"negative fp", "t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&fp=-1234567890&n=1"},
+		{"type zero", "t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=0"},
+		{"type five", "t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=5"},
+		{"type not a number", "t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=x"},
+		{"pair without equals", valid + "&broken"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if r, err := Parse(tt.in); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Parse(%q) = %+v, %v; want ErrInvalid", tt.in, r, err)
+			}
+		})
+	}
+}
+
+func TestRef(t *testing.T) {
+	a, err := Parse("t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := a.Ref(), "9960440300000000-12345-1234567890"; got != want {
+		t.Errorf("Ref() = %q, want %q", got, want)
+	}
+
+	// Тот же чек, записанный иначе, даёт ту же ссылку.
+	b, err := Parse("n=1&fp=1234567890&i=0012345&fn=9960440300000000&s=2450&t=20261017T1930")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Ref() != b.Ref() {
+		t.Errorf("Ref() differs for the same receipt: %q vs %q", a.Ref(), b.Ref())
+	}
+
+	// Другой документ того же накопителя — другая ссылка.
+	c, err := Parse("t=20261017T1930&s=2450.00&fn=9960440300000000&i=12346&fp=1234567890&n=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Ref() == c.Ref() {
+		t.Errorf("different receipts share Ref %q", a.Ref())
+	}
+}
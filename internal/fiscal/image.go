@@ -0,0 +1,53 @@
+package fiscal
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+// Ограничения на изображение с QR-кодом: размер файла и число пикселей, чтобы
+// маленький файл с огромными объявленными размерами не занял гигабайты памяти.
+const (
+	MaxImageBytes  = 10 << 20
+	MaxImagePixels = 50_000_000
+)
+
+// DecodeImage находит QR-код на изображении (JPEG или PNG) и возвращает его текст.
+func DecodeImage(r io.Reader) (string, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxImageBytes+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > MaxImageBytes {
+		return "", fmt.Errorf("%w: изображение больше %d МБ", ErrInvalid, MaxImageBytes>>20)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%w: не удалось прочитать изображение: %v", ErrInvalid, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > MaxImagePixels/cfg.Height {
+		return "", fmt.Errorf("%w: слишком большое изображение %dx%d", ErrInvalid, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("%w: не удалось прочитать изображение: %v", ErrInvalid, err)
+	}
+	bmp, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	hints := map[gozxing.DecodeHintType]interface{}{gozxing.DecodeHintType_TRY_HARDER: true}
+	result, err := qrcode.NewQRCodeReader().Decode(bmp, hints)
+	if err != nil {
+		return "", fmt.Errorf("%w: QR-код на изображении не найден", ErrInvalid)
+	}
+	return result.GetText(), nil
+}
@@ -0,0 +1,63 @@
+package fiscal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func TestDecodeImage(t *testing.T) {
+	const text = "t=20261017T1930&s=2450.00&fn=9960440300000000&i=12345&fp=1234567890&n=1"
+	matrix, err := qrcode.NewQRCodeWriter().Encode(text, gozxing.BarcodeFormat_QR_CODE, 300, 300, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, matrix); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DecodeImage(&buf)
+	if err != nil {
+		t.Fatalf("DecodeImage: %v", err)
+	}
+	if got != text {
+		t.Errorf("DecodeImage = %q, want %q", got, text)
+	}
+}
+
+func TestDecodeImageErrors(t *testing.T) {
+	var blank bytes.Buffer
+	if err := png.Encode(&blank, image.NewGray(image.Rect(0, 0, 100, 100))); err != nil {
+		t.Fatal(err)
+	}
+
+	// Заголовок PNG объявляет 100000×100000 пикселей при крошечном файле.
+	bomb := append([]byte(nil), blank.Bytes()...)
+	binary.BigEndian.PutUint32(bomb[16:20], 100000)
+	binary.BigEndian.PutUint32(bomb[20:24], 100000)
+	binary.BigEndian.PutUint32(bomb[29:33], crc32.ChecksumIEEE(bomb[12:29]))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not an image", []byte("t=20261017T1930&s=2450.00")},
+		{"image without qr", blank.Bytes()},
+		{"huge dimensions", bomb},
+		{"too many bytes", make([]byte, MaxImageBytes+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeImage(bytes.NewReader(tt.data)); !errors.Is(err, ErrInvalid) {
+				t.Errorf("DecodeImage = %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package importer
+
+import (
+	"fmt"
+	"split-the-bill/internal/money"
+	"strings"
+)
+
+var nativeColumns = map[string]bool{
+	"date": true, "title": true, "amount": true, "paid_by": true, "type": true, "to": true,
+}
+
+func parseNative(result *Result, header []string, records []record) error {
+	cols := columns(header)
+	if !cols.has("date", "title", "amount", "paid_by") {
+		return fmt.Errorf("%w: нужны столбцы date, title, amount и paid_by", ErrFormat)
+	}
+	var people []int
+	for i, h := range header {
+		if h != "" && !nativeColumns[strings.ToLower(h)] {
+			people = append(people, i)
+		}
+	}
+
+	for _, rec := range records {
+		row := Row{
+			Line:   rec.line,
+			Kind:   KindExpense,
+			Title:  cols.get(rec.fields, "title"),
+			PaidBy: cols.get(rec.fields, "paid_by"),
+			To:     cols.get(rec.fields, "to"),
+		}
+		if kind := strings.ToLower(cols.get(rec.fields, "type")); kind != "" {
+			row.Kind = Kind(kind)
+		}
+
+		var err error
+		if row.Date, err = parseDate(cols.get(rec.fields, "date")); err != nil {
+			result.fail(rec.line, "%v", err)
+			continue
+		}
+		if row.Amount, err = parseAmount(cols.get(rec.fields, "amount")); err != nil || row.Amount <= 0 {
+			result.fail(rec.line, "некорректная сумма %q", cols.get(rec.fields, "amount"))
+			continue
+		}
+		if row.PaidBy == "" {
+			result.fail(rec.line, "не указан paid_by")
+			continue
+		}
+
+		switch row.Kind {
+		case KindPayment:
+			if row.To == "" {
+				result.fail(rec.line, "у платежа не указан получатель to")
+				continue
+			}
+		case KindExpense:
+			if row.Shares, err = shareColumns(header, people, rec.fields); err != nil {
+				result.fail(rec.line, "%v", err)
+				continue
+			}
+		default:
+			result.fail(rec.line, "неизвестный type %q", row.Kind)
+			continue
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return nil
+}
+
+// shareColumns собирает ненулевые доли из столбцов участников.
+func shareColumns(header []string, people []int, fields []string) ([]Share, error) {
+	var shares []Share
+	for _, i := range people {
+		amount, err := parseAmount(field(fields, i))
+		if err != nil {
+			return nil, fmt.Errorf("некорректная доля %s: %q", header[i], field(fields, i))
+		}
+		if amount < 0 {
+			amount = -amount
+		}
+		if amount != 0 {
+			shares = append(shares, Share{Name: header[i], Amount: amount})
+		}
+	}
+	return shares, nil
+}
+
+// Splitwise в столбце участника пишет его баланс по строке: заплатил минус
+// должен. Плательщик — единственный участник с положительным балансом.
+func parseSplitwise(result *Result, header []string, records []record) error {
+	cols := columns(header)
+	// Столбцы участников идут после Currency; без него их не найти.
+	if !cols.has("date", "description", "cost", "currency") {
+		return fmt.Errorf("%w: нужны столбцы Date, Description, Cost и Currency", ErrFormat)
+	}
+	people := make([]int, 0, len(header))
+	for i := cols["currency"] + 1; i < len(header); i++ {
+		if header[i] != "" {
+			people = append(people, i)
+		}
+	}
+
+	for _, rec := range records {
+		title := cols.get(rec.fields, "description")
+		dateRaw := cols.get(rec.fields, "date")
+		// Итоговая строка "Total balance" идёт без даты.
+		if dateRaw == "" || strings.EqualFold(title, "total balance") {
+			continue
+		}
+
+		date, err := parseDate(dateRaw)
+		if err != nil {
+			result.fail(rec.line, "%v", err)
+			continue
+		}
+		cost, err := parseAmount(cols.get(rec.fields, "cost"))
+		if err != nil || cost <= 0 {
+			result.fail(rec.line, "некорректная сумма %q", cols.get(rec.fields, "cost"))
+			continue
+		}
+
+		var payers, debtors []Share
+		var bad bool
+		for _, i := range people {
+			net, err := parseAmount(field(rec.fields, i))
+			if err != nil {
+				result.fail(rec.line, "некорректный баланс %s: %q", header[i], field(rec.fields, i))
+				bad = true
+				break
+			}
+			switch {
+			case net > 0:
+				payers = append(payers, Share{Name: header[i], Amount: net})
+			case net < 0:
+				debtors = append(debtors, Share{Name: header[i], Amount: -net})
+			}
+		}
+		if bad {
+			continue
+		}
+		if len(payers) != 1 {
+			if len(payers) == 0 {
+				result.fail(rec.line, "не удалось определить плательщика")
+			} else {
+				result.fail(rec.line, "несколько плательщиков в одной трате не поддерживаются")
+			}
+			continue
+		}
+		payer := payers[0]
+
+		row := Row{Line: rec.line, Kind: KindExpense, Date: date, Title: title, Amount: cost, PaidBy: payer.Name}
+		if strings.EqualFold(cols.get(rec.fields, "category"), "payment") {
+			if len(debtors) != 1 {
+				result.fail(rec.line, "у платежа должен быть ровно один получатель")
+				continue
+			}
+			row.Kind, row.To = KindPayment, debtors[0].Name
+			result.Rows = append(result.Rows, row)
+			continue
+		}
+
+		// Доля плательщика — всё, что не досталось остальным.
+		own := cost
+		for _, d := range debtors {
+			own -= d.Amount
+		}
+		if own < 0 || own != cost-payer.Amount {
+			result.fail(rec.line, "балансы участников не сходятся с суммой %s", cost)
+			continue
+		}
+		row.Shares = debtors
+		if own > 0 {
+			row.Shares = append(row.Shares, Share{Name: payer.Name, Amount: own})
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return nil
+}
+
+var tricountPrefixes = []string{"paid for ", "impacted to "}
+
+// tricountShares возвращает индексы столбцов "Paid for <имя>" и имена из них.
+func tricountShares(header []string) map[int]string {
+	shares := make(map[int]string)
+	for i, h := range header {
+		for _, prefix := range tricountPrefixes {
+			if len(h) > len(prefix) && strings.EqualFold(h[:len(prefix)], prefix) {
+				shares[i] = strings.TrimSpace(h[len(prefix):])
+			}
+		}
+	}
+	return shares
+}
+
+// Tricount пишет расходы отрицательными суммами, поэтому берём модуль.
+// Если есть столбец в валюте по умолчанию, используем его.
+func parseTricount(result *Result, header []string, records []record) error {
+	cols := columns(header)
+	shareCols := tricountShares(header)
+	if !cols.has("title", "paid by") || len(shareCols) == 0 {
+		return fmt.Errorf("%w: нужны столбцы Title, Paid by и Paid for <имя>", ErrFormat)
+	}
+	people := make([]int, 0, len(shareCols))
+	names := make([]string, len(header))
+	copy(names, header)
+	for i := range header {
+		if name, ok := shareCols[i]; ok {
+			people = append(people, i)
+			names[i] = name
+		}
+	}
+
+	amountCol := "amount"
+	if cols.has("amount in default currency") {
+		amountCol = "amount in default currency"
+	}
+	dateCol := "date"
+	if cols.has("date & time") {
+		dateCol = "date & time"
+	}
+
+	for _, rec := range records {
+		row := Row{
+			Line:   rec.line,
+			Kind:   KindExpense,
+			Title:  cols.get(rec.fields, "title"),
+			PaidBy: cols.get(rec.fields, "paid by"),
+		}
+
+		var err error
+		if row.Date, err = parseDate(cols.get(rec.fields, dateCol)); err != nil {
+			result.fail(rec.line, "%v", err)
+			continue
+		}
+		var amount money.Money
+		if amount, err = parseAmount(cols.get(rec.fields, amountCol)); err != nil || amount == 0 {
+			result.fail(rec.line, "некорректная сумма %q", cols.get(rec.fields, amountCol))
+			continue
+		}
+		row.Amount = amount.Abs()
+		if row.PaidBy == "" {
+			result.fail(rec.line, "не указан плательщик")
+			continue
+		}
+		if row.Shares, err = shareColumns(names, people, rec.fields); err != nil {
+			result.fail(rec.line, "%v", err)
+			continue
+		}
+
+		switch kind := strings.ToLower(cols.get(rec.fields, "transaction type")); kind {
+		case "", "normal", "expense":
+		case "money transfer", "transfer":
+			if len(row.Shares) != 1 {
+				result.fail(rec.line, "у перевода должен быть ровно один получатель")
+				continue
+			}
+			row.Kind, row.To, row.Shares = KindPayment, row.Shares[0].Name, nil
+		default:
+			result.fail(rec.line, "тип операции %q не поддерживается", kind)
+			continue
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return nil
+}
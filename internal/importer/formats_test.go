@@ -0,0 +1,180 @@
+package importer
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func date(day int) time.Time {
+	return time.Date(2026, time.October, day, 0, 0, 0, 0, time.Local)
+}
+
+func parseFile(t *testing.T, name string, format Format) Result {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	result, err := Parse(f, format)
+	if err != nil {
+		t.Fatalf("Parse(%s): %v", name, err)
+	}
+	return result
+}
+
+// checkErrors сверяет ошибки строк: номер строки и фрагмент сообщения.
+func checkErrors(t *testing.T, got []RowError, want map[int]string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("got %d row errors, want %d: %+v", len(got), len(want), got)
+	}
+	for _, e := range got {
+		fragment, ok := want[e.Line]
+		if !ok {
+			t.Errorf("unexpected error on line %d: %s", e.Line, e.Error)
+			continue
+		}
+		if !strings.Contains(e.Error, fragment) {
+			t.Errorf("line %d: error %q does not mention %q", e.Line, e.Error, fragment)
+		}
+	}
+}
+
+func TestParseNative(t *testing.T) {
+	result := parseFile(t, "native.csv", "")
+	if result.Format != FormatNative {
+		t.Fatalf("format = %q, want %q", result.Format, FormatNative)
+	}
+
+	want := []Row{
+		{Line: 2, Kind: KindExpense, Date: date(17), Title: "Ужин", Amount: 245000, PaidBy: "Алиса",
+			Shares: []Share{{"Алиса", 122500}, {"Борис", 122500}}},
+		{Line: 3, Kind: KindExpense, Date: date(18), Title: "Такси", Amount: 60000, PaidBy: "Борис"},
+		{Line: 4, Kind: KindPayment, Date: date(19), Title: "Возврат", Amount: 30000, PaidBy: "Борис", To: "Алиса"},
+	}
+	if !reflect.DeepEqual(result.Rows, want) {
+		t.Errorf("rows = %+v, want %+v", result.Rows, want)
+	}
+	checkErrors(t, result.Errors, map[int]string{
+		5:  "дату",
+		6:  "сумма",
+		7:  "paid_by",
+		8:  "получатель",
+		9:  "type",
+		10: "доля Алиса",
+	})
+}
+
+func TestParseNativeSemicolon(t *testing.T) {
+	data := "\xef\xbb\xbfdate;title;amount;paid_by;Алиса;Борис\n17.10.2026;Ужин;1 234,50;Борис;;1 234,50\n"
+	result, err := Parse(strings.NewReader(data), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Row{{Line: 2, Kind: KindExpense, Date: date(17), Title: "Ужин", Amount: 123450, PaidBy: "Борис",
+		Shares: []Share{{"Борис", 123450}}}}
+	if result.Format != FormatNative || !reflect.DeepEqual(result.Rows, want) || len(result.Errors) != 0 {
+		t.Errorf("Parse() = %+v, want native rows %+v", result, want)
+	}
+}
+
+func TestParseSplitwise(t *testing.T) {
+	result := parseFile(t, "splitwise.csv", "")
+	if result.Format != FormatSplitwise {
+		t.Fatalf("format = %q, want %q", result.Format, FormatSplitwise)
+	}
+
+	want := []Row{
+		{Line: 2, Kind: KindExpense, Date: date(17), Title: "Dinner", Amount: 9000, PaidBy: "Alice",
+			Shares: []Share{{"Bob", 3000}, {"Carol", 3000}, {"Alice", 3000}}},
+		{Line: 3, Kind: KindExpense, Date: date(18), Title: "Taxi", Amount: 2000, PaidBy: "Bob",
+			Shares: []Share{{"Alice", 2000}}},
+		{Line: 4, Kind: KindPayment, Date: date(19), Title: "Settle up", Amount: 3000, PaidBy: "Bob", To: "Alice"},
+	}
+	if !reflect.DeepEqual(result.Rows, want) {
+		t.Errorf("rows = %+v, want %+v", result.Rows, want)
+	}
+	checkErrors(t, result.Errors, map[int]string{
+		5: "сумма",
+		6: "несколько плательщиков",
+		7: "не сходятся",
+		8: "баланс Alice",
+		9: "плательщика",
+	})
+}
+
+func TestParseTricount(t *testing.T) {
+	result := parseFile(t, "tricount.csv", "")
+	if result.Format != FormatTricount {
+		t.Fatalf("format = %q, want %q", result.Format, FormatTricount)
+	}
+
+	want := []Row{
+		{Line: 2, Kind: KindExpense, Date: date(17), Title: "Groceries", Amount: 4550, PaidBy: "Alice",
+			Shares: []Share{{"Alice", 2275}, {"Bob", 2275}}},
+		{Line: 3, Kind: KindExpense, Date: date(18), Title: "Hotel", Amount: 20000, PaidBy: "Bob",
+			Shares: []Share{{"Alice", 20000}}},
+		{Line: 4, Kind: KindPayment, Date: date(19), Title: "Refund", Amount: 5000, PaidBy: "Bob", To: "Alice"},
+	}
+	if !reflect.DeepEqual(result.Rows, want) {
+		t.Errorf("rows = %+v, want %+v", result.Rows, want)
+	}
+	checkErrors(t, result.Errors, map[int]string{
+		5: "сумма",
+		6: "плательщик",
+		7: "ровно один получатель",
+		8: "не поддерживается",
+	})
+}
+
+func TestResultNames(t *testing.T) {
+	result := parseFile(t, "splitwise.csv", "")
+	if got, want := result.Names(), []string{"Alice", "Bob", "Carol"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+}
+
+// Принудительный формат с чужим заголовком — ошибка формата, а не строки,
+// разобранные по не тем столбцам.
+func TestParseWrongFormat(t *testing.T) {
+	tests := []struct {
+		file   string
+		format Format
+	}{
+		{"native.csv", FormatSplitwise},
+		{"native.csv", FormatTricount},
+		{"splitwise.csv", FormatNative},
+		{"splitwise.csv", FormatTricount},
+		{"tricount.csv", FormatNative},
+		{"tricount.csv", FormatSplitwise},
+		{"native.csv", "excel"},
+	}
+	for _, tt := range tests {
+		f, err := os.Open("testdata/" + tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := Parse(f, tt.format)
+		f.Close()
+		if !errors.Is(err, ErrFormat) {
+			t.Errorf("Parse(%s, %q) error = %v, want ErrFormat", tt.file, tt.format, err)
+		}
+		if len(result.Rows) != 0 {
+			t.Errorf("Parse(%s, %q) returned rows %+v", tt.file, tt.format, result.Rows)
+		}
+	}
+}
+
+func TestParseUnknownHeader(t *testing.T) {
+	for _, data := range []string{"", "a,b,c\n1,2,3\n"} {
+		if _, err := Parse(strings.NewReader(data), ""); !errors.Is(err, ErrFormat) {
+			t.Errorf("Parse(%q) error = %v, want ErrFormat", data, err)
+		}
+	}
+}
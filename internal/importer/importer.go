@@ -0,0 +1,293 @@
+// Package importer разбирает CSV с тратами: собственный формат приложения и
+// экспорт Splitwise и Tricount. Участники в файле — это имена; сопоставлять
+// их с пользователями события — задача вызывающего.
+//
+// Собственный формат (разделитель — запятая или точка с запятой):
+//
+//	date,title,amount,paid_by,type,to,Алиса,Борис
+//	2026-10-17,Ужин,2450.00,Алиса,,,1225.00,1225.00
+//	2026-10-18,Такси,600,Борис,,,,
+//	2026-10-19,Возврат,300,Борис,payment,Алиса,,
+//
+// date — дата (2006-01-02, 02.01.2006, с временем или RFC 3339); type — expense
+// (по умолчанию) или payment; to — получатель платежа. Столбцы после служебных —
+// точные доли участников; если все они пусты, трата делится поровну между
+// всеми участниками события. Столбцы type и to можно не указывать.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"split-the-bill/internal/money"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrFormat возвращается, если файл не удалось распознать как CSV известного формата.
+var ErrFormat = errors.New("неизвестный формат CSV")
+
+type Format string
+
+const (
+	FormatNative    Format = "native"
+	FormatSplitwise Format = "splitwise"
+	FormatTricount  Format = "tricount"
+)
+
+type Kind string
+
+const (
+	KindExpense Kind = "expense"
+	KindPayment Kind = "payment"
+)
+
+// Share — точная доля участника, указанного именем.
+type Share struct {
+	Name   string      `json:"name"`
+	Amount money.Money `json:"amount"`
+}
+
+// Row — трата или платёж из одной строки файла. У платежа PaidBy — плательщик,
+// To — получатель. Пустой Shares у траты означает деление поровну.
+type Row struct {
+	Line   int         `json:"line"`
+	Kind   Kind        `json:"kind"`
+	Date   time.Time   `json:"date"`
+	Title  string      `json:"title"`
+	Amount money.Money `json:"amount"`
+	PaidBy string      `json:"paid_by"`
+	To     string      `json:"to,omitempty"`
+	Shares []Share     `json:"shares,omitempty"`
+}
+
+// RowError — ошибка в строке файла; строка в Rows не попадает.
+type RowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type Result struct {
+	Format Format     `json:"format"`
+	Rows   []Row      `json:"rows"`
+	Errors []RowError `json:"errors"`
+}
+
+// Names возвращает все имена участников из строк в порядке появления.
+func (r Result) Names() []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, row := range r.Rows {
+		add(row.PaidBy)
+		add(row.To)
+		for _, s := range row.Shares {
+			add(s.Name)
+		}
+	}
+	return names
+}
+
+type record struct {
+	line   int
+	fields []string
+}
+
+// Parse читает CSV. Пустой format — определить по заголовку. Ошибки в
+// отдельных строках собираются в Result.Errors, а ошибка возвращается,
+// только если файл целиком не читается.
+func Parse(r io.Reader, format Format) (Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Result{}, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return Result{}, fmt.Errorf("%w: пустой файл", ErrFormat)
+	} else if err != nil {
+		return Result{}, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	var records []record
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return Result{}, fmt.Errorf("%w: %v", ErrFormat, err)
+		}
+		line, _ := reader.FieldPos(0)
+		if blank(fields) {
+			continue
+		}
+		records = append(records, record{line: line, fields: fields})
+	}
+
+	if format == "" {
+		format = detect(header)
+	}
+	result := Result{Format: format}
+	switch format {
+	case FormatNative:
+		err = parseNative(&result, header, records)
+	case FormatSplitwise:
+		err = parseSplitwise(&result, header, records)
+	case FormatTricount:
+		err = parseTricount(&result, header, records)
+	default:
+		err = fmt.Errorf("%w: не удалось определить формат по заголовку", ErrFormat)
+	}
+	return result, err
+}
+
+func detect(header []string) Format {
+	cols := columns(header)
+	switch {
+	case cols.has("title", "amount", "paid_by"):
+		return FormatNative
+	case cols.has("date", "description", "category", "cost", "currency"):
+		return FormatSplitwise
+	case cols.has("paid by") && len(tricountShares(header)) > 0:
+		return FormatTricount
+	}
+	return ""
+}
+
+// delimiter выбирает между запятой и точкой с запятой по первой строке.
+func delimiter(data []byte) rune {
+	first, _ := bufio.NewReader(bytes.NewReader(data)).ReadString('\n')
+	if strings.Count(first, ";") > strings.Count(first, ",") {
+		return ';'
+	}
+	return ','
+}
+
+func blank(fields []string) bool {
+	for _, f := range fields {
+		if strings.TrimSpace(f) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// columnIndex — индексы столбцов по имени в нижнем регистре.
+type columnIndex map[string]int
+
+func columns(header []string) columnIndex {
+	cols := make(columnIndex, len(header))
+	for i, h := range header {
+		key := strings.ToLower(h)
+		if _, ok := cols[key]; !ok {
+			cols[key] = i
+		}
+	}
+	return cols
+}
+
+func (c columnIndex) has(names ...string) bool {
+	for _, n := range names {
+		if _, ok := c[n]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// get возвращает значение столбца name или "", если столбца нет.
+func (c columnIndex) get(fields []string, name string) string {
+	i, ok := c[name]
+	if !ok || i >= len(fields) {
+		return ""
+	}
+	return strings.TrimSpace(fields[i])
+}
+
+func field(fields []string, i int) string {
+	if i >= len(fields) {
+		return ""
+	}
+	return strings.TrimSpace(fields[i])
+}
+
+// parseAmount понимает "1234.50", "1 234,50", "1,234.50" и суммы со знаком
+// или кодом валюты в конце, как в нашей выгрузке: "2 450.00 ₽"; пустая строка — ноль.
+// Любые другие символы — ошибка, а не молча пропущенный мусор.
+func parseAmount(s string) (money.Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	end := strings.LastIndexFunc(s, func(r rune) bool { return r >= '0' && r <= '9' })
+	if end < 0 {
+		return 0, money.ErrInvalid
+	}
+	if suffix := strings.TrimSpace(s[end+1:]); suffix != "" {
+		if _, ok := money.ParseCurrency(suffix); !ok {
+			return 0, money.ErrInvalid
+		}
+	}
+
+	var b strings.Builder
+	for _, r := range s[:end+1] {
+		switch {
+		case unicode.IsSpace(r):
+		case (r >= '0' && r <= '9') || r == '.' || r == ',' || r == '-' || r == '+':
+			b.WriteRune(r)
+		default:
+			return 0, money.ErrInvalid
+		}
+	}
+	s = b.String()
+	if strings.Contains(s, ".") {
+		s = strings.ReplaceAll(s, ",", "")
+	} else {
+		s = strings.ReplaceAll(s, ",", ".")
+	}
+	return money.Parse(s)
+}
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+	"02.01.2006",
+	"02.01.2006 15:04",
+	"02.01.2006 15:04:05",
+	"02/01/2006",
+	"02/01/2006 15:04",
+	"02/01/2006 15:04:05",
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("не удалось разобрать дату %q", s)
+}
+
+func (r *Result) fail(line int, format string, args ...any) {
+	r.Errors = append(r.Errors, RowError{Line: line, Error: fmt.Sprintf(format, args...)})
+}
@@ -0,0 +1,42 @@
+package importer
+
+import (
+	"split-the-bill/internal/money"
+	"testing"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want money.Money
+	}{
+		{"", 0},
+		{"1234.50", 123450},
+		{"1 234,50", 123450},
+		{"1,234.50", 123450},
+		{"-3.07", -307},
+		{"+12", 1200},
+		{"2 450.00 ₽", 245000},
+		{"2 450.00 ₽", 245000},
+		{"12.50 EUR", 1250},
+		{"  7,5  ", 750},
+	}
+	for _, tt := range tests {
+		got, err := parseAmount(tt.in)
+		if err != nil {
+			t.Errorf("parseAmount(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseAmount(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseAmountInvalid(t *testing.T) {
+	for _, in := range []string{"n/a", "1O0", "12abc", "₽ 12x", "12.50 euros", "$12", "1.2.3", "--"} {
+		if got, err := parseAmount(in); err == nil {
+			t.Errorf("parseAmount(%q) = %d, want error", in, got)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package ledger считает балансы и долги события по тратам, долям и платежам.
+package ledger
+
+import (
+	"gorm.io/gorm"
+	"sort"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+)
+
+// Position — из чего складывается баланс участника: сколько он заплатил
+// за траты, его доля в них, сколько он перевёл другим и сколько получил.
+type Position struct {
+	Paid     money.Money `json:"paid"`
+	Share    money.Money `json:"share"`
+	Sent     money.Money `json:"sent"`
+	Received money.Money `json:"received"`
+}
+
+// Net — чистая позиция: положительная — участнику должны, отрицательная — должен он.
+func (p Position) Net() money.Money {
+	return p.Paid - p.Share + p.Sent - p.Received
+}
+
+// Balances возвращает чистую позицию каждого участника события:
+// положительная — участнику должны, отрицательная — должен он.
+// Сумма всех балансов всегда равна нулю.
+func Balances(db *gorm.DB, eventID uint) (map[uint]money.Money, error) {
+	positions, err := Positions(db, eventID)
+	if err != nil {
+		return nil, err
+	}
+	balances := make(map[uint]money.Money, len(positions))
+	for id, p := range positions {
+		balances[id] = p.Net()
+	}
+	return balances, nil
+}
+
+// Positions возвращает разбивку баланса каждого участника события. В неё
+// попадают и бывшие участники, у которых остались траты или платежи.
+func Positions(db *gorm.DB, eventID uint) (map[uint]Position, error) {
+	positions := make(map[uint]Position)
+
+	var participants []uint
+	if err := db.Model(&models.EventParticipant{}).
+		Where("event_id = ?", eventID).
+		Pluck("user_id", &participants).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range participants {
+		positions[id] = Position{}
+	}
+
+	type row struct {
+		UserID uint
+		Amount money.Money
+	}
+	add := func(rows []row, field func(*Position) *money.Money) {
+		for _, r := range rows {
+			p := positions[r.UserID]
+			*field(&p) += r.Amount
+			positions[r.UserID] = p
+		}
+	}
+
+	var paid []row
+	if err := db.Model(&models.Expense{}).
+		Select("paid_by AS user_id, COALESCE(SUM(amount), 0) AS amount").
+		Where("event_id = ?", eventID).
+		Group("paid_by").Scan(&paid).Error; err != nil {
+		return nil, err
+	}
+	add(paid, func(p *Position) *money.Money { return &p.Paid })
+
+	var owed []row
+	if err := db.Table("expense_shares").
+		Select("expense_shares.user_id, COALESCE(SUM(expense_shares.share_amount), 0) AS amount").
+		Joins("JOIN expenses ON expense_shares.expense_id = expenses.id").
+		Where("expenses.event_id = ?", eventID).
+		Group("expense_shares.user_id").Scan(&owed).Error; err != nil {
+		return nil, err
+	}
+	add(owed, func(p *Position) *money.Money { return &p.Share })
+
+	var sent []row
+	if err := db.Model(&models.Payment{}).
+		Select("from_user AS user_id, COALESCE(SUM(amount), 0) AS amount").
+		Where("event_id = ? AND status = ?", eventID, models.PaymentConfirmed).
+		Group("from_user").Scan(&sent).Error; err != nil {
+		return nil, err
+	}
+	add(sent, func(p *Position) *money.Money { return &p.Sent })
+
+	var received []row
+	if err := db.Model(&models.Payment{}).
+		Select("to_user AS user_id, COALESCE(SUM(amount), 0) AS amount").
+		Where("event_id = ? AND status = ?", eventID, models.PaymentConfirmed).
+		Group("to_user").Scan(&received).Error; err != nil {
+		return nil, err
+	}
+	add(received, func(p *Position) *money.Money { return &p.Received })
+
+	return positions, nil
+}
+
+type Transfer struct {
+	FromUser uint        `json:"from_user"`
+	ToUser   uint        `json:"to_user"`
+	Amount   money.Money `json:"amount"`
+}
+
+// Simplify строит список переводов, закрывающий все балансы. Жадно сводит
+// самого крупного должника с самым крупным кредитором: каждый шаг закрывает
+// хотя бы один баланс, поэтому при n ненулевых балансах выходит не более
+// n-1 переводов. Минимально возможное число переводов не гарантируется.
+func Simplify(balances map[uint]money.Money) []Transfer {
+	type position struct {
+		userID uint
+		amount money.Money
+	}
+
+	var debtors, creditors []position
+	for id, b := range balances {
+		switch {
+		case b < 0:
+			debtors = append(debtors, position{id, -b})
+		case b > 0:
+			creditors = append(creditors, position{id, b})
+		}
+	}
+
+	byAmount := func(p []position) func(i, j int) bool {
+		return func(i, j int) bool {
+			if p[i].amount != p[j].amount {
+				return p[i].amount > p[j].amount
+			}
+			return p[i].userID < p[j].userID
+		}
+	}
+	sort.Slice(debtors, byAmount(debtors))
+	sort.Slice(creditors, byAmount(creditors))
+
+	var transfers []Transfer
+	for len(debtors) > 0 && len(creditors) > 0 {
+		d, c := &debtors[0], &creditors[0]
+		amount := min(d.amount, c.amount)
+		transfers = append(transfers, Transfer{FromUser: d.userID, ToUser: c.userID, Amount: amount})
+		d.amount -= amount
+		c.amount -= amount
+
+		if d.amount == 0 {
+			debtors = debtors[1:]
+		} else {
+			sort.Slice(debtors, byAmount(debtors))
+		}
+		if c.amount == 0 {
+			creditors = creditors[1:]
+		} else {
+			sort.Slice(creditors, byAmount(creditors))
+		}
+	}
+	return transfers
+}
@@ -0,0 +1,131 @@
+package ledger
+
+import (
+	"reflect"
+	"split-the-bill/internal/money"
+	"testing"
+)
+
+// settle применяет переводы к балансам и возвращает то, что осталось.
+func settle(balances map[uint]money.Money, transfers []Transfer) map[uint]money.Money {
+	left := make(map[uint]money.Money, len(balances))
+	for id, b := range balances {
+		left[id] = b
+	}
+	for _, t := range transfers {
+		left[t.FromUser] += t.Amount
+		left[t.ToUser] -= t.Amount
+	}
+	return left
+}
+
+func TestSimplify(t *testing.T) {
+	tests := []struct {
+		name     string
+		balances map[uint]money.Money
+		want     []Transfer
+	}{
+		{
+			name:     "one debtor one creditor",
+			balances: map[uint]money.Money{1: 500, 2: -500},
+			want:     []Transfer{{FromUser: 2, ToUser: 1, Amount: 500}},
+		},
+		{
+			name:     "one creditor many debtors",
+			balances: map[uint]money.Money{1: 900, 2: -300, 3: -400, 4: -200},
+			want: []Transfer{
+				{FromUser: 3, ToUser: 1, Amount: 400},
+				{FromUser: 2, ToUser: 1, Amount: 300},
+				{FromUser: 4, ToUser: 1, Amount: 200},
+			},
+		},
+		{
+			name:     "largest debtor pays largest creditor",
+			balances: map[uint]money.Money{1: 700, 2: 300, 3: -600, 4: -400},
+			want: []Transfer{
+				{FromUser: 3, ToUser: 1, Amount: 600},
+				{FromUser: 4, ToUser: 2, Amount: 300},
+				{FromUser: 4, ToUser: 1, Amount: 100},
+			},
+		},
+		{
+			name:     "ties broken by user id",
+			balances: map[uint]money.Money{5: 100, 2: 100, 9: -100, 4: -100},
+			want: []Transfer{
+				{FromUser: 4, ToUser: 2, Amount: 100},
+				{FromUser: 9, ToUser: 5, Amount: 100},
+			},
+		},
+		{
+			name:     "zero balances are skipped",
+			balances: map[uint]money.Money{1: 0, 2: 250, 3: -250, 4: 0},
+			want:     []Transfer{{FromUser: 3, ToUser: 2, Amount: 250}},
+		},
+		{
+			name:     "all settled",
+			balances: map[uint]money.Money{1: 0, 2: 0},
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Simplify(tt.balances)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Simplify(%v) = %v, want %v", tt.balances, got, tt.want)
+			}
+		})
+	}
+}
+
+// Свойства плана на балансах похожей на жизнь поездки: план закрывает всех,
+// переводы положительны и их не больше, чем ненулевых балансов минус один.
+func TestSimplifySettlesEveryone(t *testing.T) {
+	tests := []map[uint]money.Money{
+		{1: 12345, 2: -2345, 3: -5000, 4: -5000},
+		{1: -1, 2: -1, 3: -1, 4: 3},
+		{1: 4000, 2: 2500, 3: -1500, 4: -1500, 5: -1700, 6: -1800},
+		{10: 33333, 20: 33334, 30: -33333, 40: -33334},
+		{1: 1, 2: 2, 3: 3, 4: 4, 5: -10},
+	}
+	for _, balances := range tests {
+		var total money.Money
+		nonZero := 0
+		for _, b := range balances {
+			total += b
+			if b != 0 {
+				nonZero++
+			}
+		}
+		if total != 0 {
+			t.Fatalf("test balances %v sum to %d, not zero", balances, total)
+		}
+
+		transfers := Simplify(balances)
+		if len(transfers) > nonZero-1 {
+			t.Errorf("Simplify(%v) made %d transfers, want at most %d", balances, len(transfers), nonZero-1)
+		}
+		for _, tr := range transfers {
+			if tr.Amount <= 0 || tr.FromUser == tr.ToUser {
+				t.Errorf("Simplify(%v): bad transfer %+v", balances, tr)
+			}
+		}
+		for id, left := range settle(balances, transfers) {
+			if left != 0 {
+				t.Errorf("Simplify(%v): user %d left with %d", balances, id, left)
+			}
+		}
+	}
+}
+
+// Порядок обхода map в Go случаен, а план должен быть одним и тем же.
+func TestSimplifyDeterministic(t *testing.T) {
+	balances := map[uint]money.Money{1: 500, 2: 500, 3: 500, 4: -500, 5: -500, 6: -500}
+	want := Simplify(balances)
+	for i := 0; i < 50; i++ {
+		if got := Simplify(balances); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Simplify returned %v, previously %v", got, want)
+		}
+	}
+}
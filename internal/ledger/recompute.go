@@ -0,0 +1,163 @@
+package ledger
+
+import (
+	"gorm.io/gorm"
+	"sort"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/money"
+)
+
+type pair struct {
+	from, to uint
+}
+
+// PairwiseDebts сводит долги по парам участников: доли в чужих тратах
+// увеличивают долг перед плательщиком, платежи его уменьшают.
+func PairwiseDebts(db *gorm.DB, eventID uint) ([]Transfer, error) {
+	var owed []Transfer
+	if err := db.Table("expense_shares").
+		Select("expense_shares.user_id AS from_user, expenses.paid_by AS to_user, COALESCE(SUM(expense_shares.share_amount), 0) AS amount").
+		Joins("JOIN expenses ON expense_shares.expense_id = expenses.id").
+		Where("expenses.event_id = ? AND expense_shares.user_id <> expenses.paid_by", eventID).
+		Group("expense_shares.user_id, expenses.paid_by").Scan(&owed).Error; err != nil {
+		return nil, err
+	}
+
+	// Подтверждённый платёж from -> to эквивалентен встречному долгу to -> from.
+	var paid []Transfer
+	if err := db.Model(&models.Payment{}).
+		Select("to_user AS from_user, from_user AS to_user, COALESCE(SUM(amount), 0) AS amount").
+		Where("event_id = ? AND from_user <> to_user AND status = ?", eventID, models.PaymentConfirmed).
+		Group("from_user, to_user").Scan(&paid).Error; err != nil {
+		return nil, err
+	}
+
+	return netDebts(append(owed, paid...)), nil
+}
+
+// netDebts сводит встречные долги каждой пары в один перевод. Пары с нулевым
+// итогом пропускаются, результат упорядочен по отправителю и получателю.
+func netDebts(debts []Transfer) []Transfer {
+	net := make(map[pair]money.Money)
+	for _, d := range debts {
+		if d.FromUser < d.ToUser {
+			net[pair{d.FromUser, d.ToUser}] += d.Amount
+		} else {
+			net[pair{d.ToUser, d.FromUser}] -= d.Amount
+		}
+	}
+
+	var transfers []Transfer
+	for p, amount := range net {
+		switch {
+		case amount > 0:
+			transfers = append(transfers, Transfer{FromUser: p.from, ToUser: p.to, Amount: amount})
+		case amount < 0:
+			transfers = append(transfers, Transfer{FromUser: p.to, ToUser: p.from, Amount: -amount})
+		}
+	}
+	sort.Slice(transfers, func(i, j int) bool {
+		if transfers[i].FromUser != transfers[j].FromUser {
+			return transfers[i].FromUser < transfers[j].FromUser
+		}
+		return transfers[i].ToUser < transfers[j].ToUser
+	})
+	return transfers
+}
+
+// Recompute приводит таблицу debts события в соответствие с тратами и платежами.
+// Долги — чистая функция леджера: попарное сведение либо, если в событии
+// включено упрощение, план переводов из Simplify. Строки обновляются на месте,
+// чтобы у открытых долгов сохранялись идентификаторы; погашенные пары остаются
+// с нулевой суммой и is_settled = true. Вызывать внутри транзакции мутации.
+func Recompute(tx *gorm.DB, eventID uint) error {
+	var event models.Event
+	if err := tx.First(&event, eventID).Error; err != nil {
+		return err
+	}
+
+	var transfers []Transfer
+	if event.SimplifyDebts {
+		balances, err := Balances(tx, eventID)
+		if err != nil {
+			return err
+		}
+		transfers = Simplify(balances)
+	} else {
+		var err error
+		if transfers, err = PairwiseDebts(tx, eventID); err != nil {
+			return err
+		}
+	}
+
+	var existing []models.Debt
+	if err := tx.Where("event_id = ?", eventID).Order("id").Find(&existing).Error; err != nil {
+		return err
+	}
+
+	changes := reconcile(eventID, existing, transfers)
+	for i := range changes.remove {
+		if err := tx.Delete(&changes.remove[i]).Error; err != nil {
+			return err
+		}
+	}
+	for i := range changes.update {
+		if err := tx.Save(&changes.update[i]).Error; err != nil {
+			return err
+		}
+	}
+	for i := range changes.create {
+		if err := tx.Create(&changes.create[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// debtChanges — что сделать со строками debts, чтобы они совпали с переводами.
+type debtChanges struct {
+	update, create, remove []models.Debt
+}
+
+// reconcile сравнивает строки долгов события с переводами. Первая строка пары
+// получает сумму перевода или закрывается, дубли пары удаляются, для новых пар
+// создаются строки. Уже совпадающие строки не трогаются, поэтому для
+// согласованной таблицы изменений нет.
+func reconcile(eventID uint, existing []models.Debt, transfers []Transfer) debtChanges {
+	target := make(map[pair]money.Money, len(transfers))
+	for _, t := range transfers {
+		target[pair{t.FromUser, t.ToUser}] = t.Amount
+	}
+
+	var changes debtChanges
+	seen := make(map[pair]bool, len(existing))
+	for _, debt := range existing {
+		p := pair{debt.FromUser, debt.ToUser}
+		if seen[p] {
+			changes.remove = append(changes.remove, debt)
+			continue
+		}
+		seen[p] = true
+
+		amount, open := target[p]
+		if debt.Amount == amount && debt.IsSettled == !open {
+			continue
+		}
+		debt.Amount = amount
+		debt.IsSettled = !open
+		changes.update = append(changes.update, debt)
+	}
+
+	for _, t := range transfers {
+		if seen[pair{t.FromUser, t.ToUser}] {
+			continue
+		}
+		changes.create = append(changes.create, models.Debt{
+			EventID:  eventID,
+			FromUser: t.FromUser,
+			ToUser:   t.ToUser,
+			Amount:   t.Amount,
+		})
+	}
+	return changes
+}
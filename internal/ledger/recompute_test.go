@@ -0,0 +1,173 @@
+package ledger
+
+import (
+	"reflect"
+	"split-the-bill/internal/models"
+	"testing"
+)
+
+func TestNetDebts(t *testing.T) {
+	tests := []struct {
+		name  string
+		debts []Transfer
+		want  []Transfer
+	}{
+		{
+			name:  "single debt",
+			debts: []Transfer{{FromUser: 2, ToUser: 1, Amount: 500}},
+			want:  []Transfer{{FromUser: 2, ToUser: 1, Amount: 500}},
+		},
+		{
+			name: "opposite debts net out",
+			debts: []Transfer{
+				{FromUser: 1, ToUser: 2, Amount: 700},
+				{FromUser: 2, ToUser: 1, Amount: 300},
+			},
+			want: []Transfer{{FromUser: 1, ToUser: 2, Amount: 400}},
+		},
+		{
+			name: "payment larger than debt flips direction",
+			debts: []Transfer{
+				{FromUser: 3, ToUser: 1, Amount: 200},
+				{FromUser: 1, ToUser: 3, Amount: 500},
+			},
+			want: []Transfer{{FromUser: 1, ToUser: 3, Amount: 300}},
+		},
+		{
+			name: "settled pair is dropped",
+			debts: []Transfer{
+				{FromUser: 1, ToUser: 2, Amount: 250},
+				{FromUser: 2, ToUser: 1, Amount: 250},
+				{FromUser: 3, ToUser: 2, Amount: 100},
+			},
+			want: []Transfer{{FromUser: 3, ToUser: 2, Amount: 100}},
+		},
+		{
+			name: "sorted by sender then receiver",
+			debts: []Transfer{
+				{FromUser: 3, ToUser: 1, Amount: 10},
+				{FromUser: 2, ToUser: 3, Amount: 20},
+				{FromUser: 2, ToUser: 1, Amount: 30},
+			},
+			want: []Transfer{
+				{FromUser: 2, ToUser: 1, Amount: 30},
+				{FromUser: 2, ToUser: 3, Amount: 20},
+				{FromUser: 3, ToUser: 1, Amount: 10},
+			},
+		},
+		{
+			name: "empty",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := netDebts(tt.debts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("netDebts(%v) = %v, want %v", tt.debts, got, tt.want)
+			}
+		})
+	}
+}
+
+// apply применяет изменения к таблице долгов так же, как Recompute.
+func apply(existing []models.Debt, changes debtChanges) []models.Debt {
+	removed := make(map[uint]bool)
+	for _, d := range changes.remove {
+		removed[d.ID] = true
+	}
+	updated := make(map[uint]models.Debt)
+	for _, d := range changes.update {
+		updated[d.ID] = d
+	}
+
+	var debts []models.Debt
+	nextID := uint(1)
+	for _, d := range existing {
+		nextID = max(nextID, d.ID+1)
+		if removed[d.ID] {
+			continue
+		}
+		if u, ok := updated[d.ID]; ok {
+			d = u
+		}
+		debts = append(debts, d)
+	}
+	for _, d := range changes.create {
+		d.ID = nextID
+		nextID++
+		debts = append(debts, d)
+	}
+	return debts
+}
+
+func TestReconcile(t *testing.T) {
+	existing := []models.Debt{
+		{ID: 1, EventID: 7, FromUser: 2, ToUser: 1, Amount: 500},
+		{ID: 2, EventID: 7, FromUser: 3, ToUser: 1, Amount: 300},
+		{ID: 3, EventID: 7, FromUser: 2, ToUser: 1, Amount: 100},
+		{ID: 4, EventID: 7, FromUser: 4, ToUser: 1, Amount: 900},
+	}
+	transfers := []Transfer{
+		{FromUser: 2, ToUser: 1, Amount: 500},
+		{FromUser: 3, ToUser: 1, Amount: 250},
+		{FromUser: 4, ToUser: 3, Amount: 100},
+	}
+
+	got := reconcile(7, existing, transfers)
+	want := debtChanges{
+		update: []models.Debt{
+			{ID: 2, EventID: 7, FromUser: 3, ToUser: 1, Amount: 250},
+			{ID: 4, EventID: 7, FromUser: 4, ToUser: 1, Amount: 0, IsSettled: true},
+		},
+		create: []models.Debt{{EventID: 7, FromUser: 4, ToUser: 3, Amount: 100}},
+		remove: []models.Debt{{ID: 3, EventID: 7, FromUser: 2, ToUser: 1, Amount: 100}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("reconcile() = %+v, want %+v", got, want)
+	}
+}
+
+// Повторный пересчёт без изменений в леджере ничего не меняет, а закрытый
+// долг снова открывается той же строкой.
+func TestReconcileIdempotent(t *testing.T) {
+	steps := [][]Transfer{
+		{{FromUser: 2, ToUser: 1, Amount: 500}, {FromUser: 3, ToUser: 1, Amount: 300}},
+		{{FromUser: 2, ToUser: 1, Amount: 200}},
+		{},
+		{{FromUser: 3, ToUser: 1, Amount: 150}, {FromUser: 1, ToUser: 2, Amount: 50}},
+	}
+
+	var debts []models.Debt
+	for i, transfers := range steps {
+		debts = apply(debts, reconcile(7, debts, transfers))
+
+		open := make(map[pair]int)
+		for _, d := range debts {
+			if !d.IsSettled {
+				open[pair{d.FromUser, d.ToUser}]++
+			}
+		}
+		if len(open) != len(transfers) {
+			t.Fatalf("step %d: %d open debts, want %d: %+v", i, len(open), len(transfers), debts)
+		}
+		for _, tr := range transfers {
+			if open[pair{tr.FromUser, tr.ToUser}] != 1 {
+				t.Fatalf("step %d: no open debt for %+v: %+v", i, tr, debts)
+			}
+		}
+
+		if again := reconcile(7, debts, transfers); !reflect.DeepEqual(again, debtChanges{}) {
+			t.Errorf("step %d: second reconcile changed %+v", i, again)
+		}
+	}
+
+	// Пара 3 -> 1 закрылась на шаге 2 и открылась на шаге 4 — в той же строке.
+	ids := 0
+	for _, d := range debts {
+		if d.FromUser == 3 && d.ToUser == 1 {
+			ids++
+		}
+	}
+	if ids != 1 {
+		t.Errorf("pair 3 -> 1 has %d rows, want 1: %+v", ids, debts)
+	}
+}
@@ -15,7 +15,7 @@ func AuthMiddleware(secret string, db *gorm.DB, log *slog.Logger) gin.HandlerFun
 		authHeader := c.GetHeader("Authorization")
 		if !strings.HasPrefix(authHeader, "Bearer ") {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid Authorization header"})
-			log.Error("Missing or invalid Authorization header", c.Params)
+			log.Error("Missing or invalid Authorization header", "path", c.Request.URL.Path)
 			c.Abort()
 			return
 		}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"split-the-bill/internal/common"
+	"split-the-bill/internal/models"
+)
+
+// Ключи контекста, которые заполняют EventMember и ExpenseMember.
+const (
+	EventKey       = "event"
+	ParticipantKey = "participant"
+	ExpenseKey     = "expense"
+)
+
+// EventMember пускает к маршрутам /events/:id только участников события.
+func EventMember(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var event models.Event
+		if err := db.First(&event, common.ParseUintParam(c.Param("id"))).Error; err != nil {
+			abortLookup(c, err, "event")
+			return
+		}
+		if !authorizeEvent(c, db, event) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// ExpenseMember пускает к маршрутам /expenses/:id только участников события, которому принадлежит трата.
+func ExpenseMember(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var expense models.Expense
+		if err := db.First(&expense, common.ParseUintParam(c.Param("id"))).Error; err != nil {
+			abortLookup(c, err, "expense")
+			return
+		}
+
+		var event models.Event
+		if err := db.First(&event, expense.EventID).Error; err != nil {
+			abortLookup(c, err, "event")
+			return
+		}
+		if !authorizeEvent(c, db, event) {
+			return
+		}
+		c.Set(ExpenseKey, expense)
+		c.Next()
+	}
+}
+
+func authorizeEvent(c *gin.Context, db *gorm.DB, event models.Event) bool {
+	userID := c.GetUint("user_id")
+
+	var participant models.EventParticipant
+	err := db.Where("event_id = ? AND user_id = ?", event.ID, userID).First(&participant).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not a participant of this event"})
+		return false
+	} else if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check event access"})
+		return false
+	}
+
+	c.Set(EventKey, event)
+	c.Set(ParticipantKey, participant)
+	return true
+}
+
+func abortLookup(c *gin.Context, err error, entity string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": entity + " not found"})
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to load " + entity})
+}
+
+// RequireRole ставится после EventMember/ExpenseMember и пропускает только
+// участников с ролью не ниже role.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		participant, ok := Participant(c)
+		if !ok || !participant.HasRole(role) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "requires " + role + " role"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Participant возвращает участника события, загруженного EventMember или ExpenseMember.
+func Participant(c *gin.Context) (models.EventParticipant, bool) {
+	v, ok := c.Get(ParticipantKey)
+	if !ok {
+		return models.EventParticipant{}, false
+	}
+	participant, ok := v.(models.EventParticipant)
+	return participant, ok
+}
+
+// Expense возвращает трату, загруженную ExpenseMember.
+func Expense(c *gin.Context) (models.Expense, bool) {
+	v, ok := c.Get(ExpenseKey)
+	if !ok {
+		return models.Expense{}, false
+	}
+	expense, ok := v.(models.Expense)
+	return expense, ok
+}
+
+// SystemAdmin пускает только администраторов сервиса — им доступны события,
+// в которых они не участвуют.
+func SystemAdmin(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user models.User
+		if err := db.First(&user, c.GetUint("user_id")).Error; err != nil {
+			abortLookup(c, err, "user")
+			return
+		}
+		if !user.IsAdmin {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "system admin only"})
+			return
+		}
+		c.Next()
+	}
+}
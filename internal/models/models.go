@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"split-the-bill/internal/money"
+	"time"
+)
 
 type User struct {
 	ID        uint      `gorm:"primaryKey"`
@@ -9,51 +12,291 @@ type User struct {
 	Email     *string   `gorm:"unique"`
 	CreatedAt time.Time `json:"created_at"`
 	Password  string    `json:"-"`
+	// GuestEventID задан у гостя — участника без аккаунта, который существует
+	// только внутри одного события и позже может быть присвоен настоящим пользователем.
+	GuestEventID *uint `gorm:"index" json:"guest_event_id,omitempty" binding:"-"`
+	// IsAdmin — администратор сервиса. Через API не выставляется, только в БД.
+	IsAdmin bool `gorm:"not null;default:false" json:"-"`
+}
+
+func (u User) IsGuest() bool {
+	return u.GuestEventID != nil
+}
+
+// UserPaymentDetails — реквизиты, по которым пользователю переводят деньги;
+// из них собираются платёжные QR-коды для его должников.
+type UserPaymentDetails struct {
+	ID          uint      `gorm:"primaryKey" json:"-"`
+	UserID      uint      `gorm:"uniqueIndex" json:"user_id"`
+	Name        string    `json:"name"`
+	BankName    string    `json:"bank_name,omitempty"`
+	BIC         string    `json:"bic,omitempty"`
+	Account     string    `json:"account,omitempty"`
+	CorrAccount string    `json:"corr_account,omitempty"`
+	INN         string    `json:"inn,omitempty"`
+	IBAN        string    `json:"iban,omitempty"`
+	SWIFT       string    `json:"swift,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 type Event struct {
-	ID        uint      `gorm:"primaryKey"`
-	Name      string    `json:"name"`
-	CreatedBy uint      `json:"created_by"`
-	CreatedAt time.Time `json:"created_at"`
+	ID              uint      `gorm:"primaryKey"`
+	Name            string    `json:"name"`
+	CreatedBy       uint      `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	RemainderPolicy string    `gorm:"default:payer" json:"remainder_policy"`
+	SimplifyDebts   bool      `json:"simplify_debts"`
+	Currency        string    `gorm:"default:RUB" json:"currency"`
+}
+
+// Роли участника события, от старшей к младшей.
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+	RoleViewer = "viewer"
+)
+
+var roleRank = map[string]int{
+	RoleViewer: 1,
+	RoleMember: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+func ValidRole(role string) bool {
+	return roleRank[role] > 0
 }
 
 type EventParticipant struct {
-	ID      uint `gorm:"primaryKey"`
-	EventID uint `json:"event_id"`
-	UserID  uint `json:"user_id"`
+	ID      uint   `gorm:"primaryKey"`
+	EventID uint   `gorm:"index" json:"event_id"`
+	UserID  uint   `gorm:"index" json:"user_id"`
+	Role    string `gorm:"default:member" json:"role"`
+}
+
+// HasRole сообщает, не ниже ли роль участника, чем role.
+func (p EventParticipant) HasRole(role string) bool {
+	return roleRank[p.Role] >= roleRank[role]
 }
 
 type Expense struct {
-	ID      uint      `gorm:"primaryKey"`
-	EventID uint      `json:"event_id"`
-	Title   string    `json:"title"`
-	Amount  float64   `json:"amount"`
-	PaidBy  uint      `json:"paid_by"`
-	PaidAt  time.Time `json:"created_at"`
+	ID        uint        `gorm:"primaryKey"`
+	EventID   uint        `gorm:"uniqueIndex:idx_expense_fiscal_ref" json:"event_id"`
+	Title     string      `json:"title"`
+	Amount    money.Money `json:"amount"`
+	PaidBy    uint        `json:"paid_by"`
+	PaidAt    time.Time   `json:"created_at"`
+	SplitMode string      `gorm:"default:exact" json:"split_mode"`
+	CreatedBy uint        `json:"created_by"`
+
+	RemainderPolicy string `gorm:"default:payer" json:"remainder_policy"`
+	RemainderSeed   int64  `json:"remainder_seed"`
+
+	// RecurringExpenseID и OccurrenceAt заданы у трат, созданных по регулярному
+	// шаблону; уникальный индекс не даёт создать одно повторение дважды.
+	RecurringExpenseID *uint      `gorm:"uniqueIndex:idx_expense_occurrence" json:"recurring_expense_id,omitempty"`
+	OccurrenceAt       *time.Time `gorm:"uniqueIndex:idx_expense_occurrence" json:"occurrence_at,omitempty"`
+
+	// FiscalRef — реквизиты чека ФНС (ФН, ФД, ФП), если трата создана по его QR-коду.
+	FiscalRef *string `gorm:"uniqueIndex:idx_expense_fiscal_ref" json:"fiscal_ref,omitempty"`
+
+	// Subtotal — сумма до налогов, чаевых и скидок; задан, только если у траты есть надбавки.
+	Subtotal money.Money `json:"subtotal,omitempty"`
+
+	Shares      []ExpenseShare      `gorm:"foreignKey:ExpenseID" json:"shares,omitempty"`
+	Items       []ExpenseItem       `gorm:"foreignKey:ExpenseID" json:"items,omitempty"`
+	Adjustments []ExpenseAdjustment `gorm:"foreignKey:ExpenseID" json:"adjustments,omitempty"`
+}
+
+// ExpenseItem — позиция чека в трате с split_mode = itemized.
+type ExpenseItem struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	ExpenseID uint        `gorm:"index" json:"expense_id"`
+	Name      string      `json:"name"`
+	Quantity  int64       `gorm:"default:1" json:"quantity"`
+	UnitPrice money.Money `json:"unit_price"`
+	Total     money.Money `json:"total"`
+
+	Assignments []ExpenseItemAssignment `gorm:"foreignKey:ItemID" json:"assignments"`
+}
+
+// ExpenseItemAssignment — кто ел позицию; стоимость делится пропорционально Portion.
+type ExpenseItemAssignment struct {
+	ID      uint  `gorm:"primaryKey" json:"id"`
+	ItemID  uint  `gorm:"index" json:"item_id"`
+	UserID  uint  `json:"user_id"`
+	Portion int64 `gorm:"default:1" json:"portion"`
 }
 
 type ExpenseShare struct {
-	ID          uint    `gorm:"primaryKey"`
-	ExpenseID   uint    `json:"expense_id"`
-	UserID      uint    `json:"user_id"`
-	ShareAmount float64 `json:"share_amount"`
+	ID          uint          `gorm:"primaryKey"`
+	ExpenseID   uint          `gorm:"index" json:"expense_id"`
+	UserID      uint          `gorm:"index" json:"user_id"`
+	ShareAmount money.Money   `json:"share_amount"`
+	Percent     money.Percent `json:"percent,omitempty"`
+	Weight      int64         `json:"weight,omitempty"`
+
+	// Разбивка доли: подытог и части каждой надбавки (скидка отрицательна).
+	Subtotal money.Money `json:"subtotal,omitempty"`
+	Tax      money.Money `json:"tax,omitempty"`
+	Tip      money.Money `json:"tip,omitempty"`
+	Service  money.Money `json:"service,omitempty"`
+	Discount money.Money `json:"discount,omitempty"`
 }
 
+// ExpenseAdjustment — налог, чаевые, сервисный сбор или скидка к трате.
+// Percent или Amount — то, что ввёл пользователь, Total — итоговая сумма со знаком.
+type ExpenseAdjustment struct {
+	ID           uint          `gorm:"primaryKey" json:"id"`
+	ExpenseID    uint          `gorm:"index" json:"expense_id"`
+	Kind         string        `json:"kind"`
+	Percent      money.Percent `json:"percent,omitempty"`
+	Amount       money.Money   `json:"amount,omitempty"`
+	Distribution string        `gorm:"default:proportional" json:"distribution"`
+	Total        money.Money   `json:"total"`
+}
+
+// Attachment — чек или фото, приложенное к трате. Сам файл и его миниатюра
+// лежат в хранилище под ключами StorageKey и ThumbnailKey.
+type Attachment struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ExpenseID    uint      `gorm:"index" json:"expense_id"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	HasThumbnail bool      `json:"has_thumbnail"`
+	UploadedBy   uint      `json:"uploaded_by"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Открытые долги пользователя по всем событиям ищутся по частичным индексам.
 type Debt struct {
-	ID        uint    `gorm:"primaryKey"`
-	EventID   uint    `json:"event_id"`
-	FromUser  uint    `json:"from_user"`
-	ToUser    uint    `json:"to_user"`
-	Amount    float64 `json:"amount"`
-	IsSettled bool    `json:"is_settled"`
+	ID        uint        `gorm:"primaryKey"`
+	EventID   uint        `gorm:"index" json:"event_id"`
+	FromUser  uint        `gorm:"index:idx_debts_open_from,where:is_settled = false" json:"from_user"`
+	ToUser    uint        `gorm:"index:idx_debts_open_to,where:is_settled = false" json:"to_user"`
+	Amount    money.Money `json:"amount"`
+	IsSettled bool        `json:"is_settled"`
 }
 
+// Статусы платежа. Долги уменьшают только подтверждённые платежи.
+const (
+	PaymentPending   = "pending"
+	PaymentConfirmed = "confirmed"
+	PaymentDisputed  = "disputed"
+)
+
 type Payment struct {
-	ID       uint      `gorm:"primaryKey"`
-	FromUser uint      `json:"from_user"`
-	ToUser   uint      `json:"to_user"`
-	Amount   float64   `json:"amount"`
-	PaidAt   time.Time `json:"created_at"`
-	EventID  uint      `json:"event_id"`
+	ID       uint        `gorm:"primaryKey"`
+	FromUser uint        `gorm:"index" json:"from_user"`
+	ToUser   uint        `gorm:"index" json:"to_user"`
+	Amount   money.Money `json:"amount"`
+	PaidAt   time.Time   `json:"created_at"`
+	EventID  uint        `gorm:"index" json:"event_id"`
+	Status   string      `gorm:"default:confirmed" json:"status"`
+
+	// SettlementID задан у платежей, созданных взаимозачётом между событиями.
+	SettlementID *uint `gorm:"index" json:"settlement_id,omitempty"`
+
+	// ReversalOf задан у сторнирующей записи: она повторяет отменённый платёж
+	// с обратным знаком суммы, а Reason объясняет, почему его отменили.
+	ReversalOf *uint  `gorm:"uniqueIndex" json:"reversal_of,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+
+	History []PaymentStatusChange `gorm:"foreignKey:PaymentID" json:"history,omitempty"`
+}
+
+// PaymentStatusChange — запись истории платежа: кто и когда перевёл его в статус Status.
+type PaymentStatusChange struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	PaymentID uint      `gorm:"index" json:"payment_id"`
+	Status    string    `json:"status"`
+	ChangedBy uint      `json:"changed_by"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Settlement — взаимозачёт долгов двух пользователей по всем общим событиям:
+// один реальный перевод Amount от FromUser к ToUser, который раскладывается
+// на платежи в каждом событии так, чтобы закрыть долги пары.
+type Settlement struct {
+	ID        uint        `gorm:"primaryKey" json:"id"`
+	FromUser  uint        `gorm:"index" json:"from_user"`
+	ToUser    uint        `gorm:"index" json:"to_user"`
+	Amount    money.Money `json:"amount"`
+	Currency  string      `json:"currency"`
+	CreatedBy uint        `json:"created_by"`
+	CreatedAt time.Time   `json:"created_at"`
+
+	Payments []Payment `gorm:"foreignKey:SettlementID" json:"payments,omitempty"`
+}
+
+// Статусы приглашения в событие.
+const (
+	InviteStatusPending  = "pending"
+	InviteStatusAccepted = "accepted"
+	InviteStatusDeclined = "declined"
+	InviteStatusRevoked  = "revoked"
+)
+
+// EventInvite — приглашение в событие. С Email это персональное приглашение,
+// без него — ссылка или код, которым может воспользоваться любой (до MaxUses раз,
+// 0 — без ограничений). С GuestID принявший приглашение забирает себе историю гостя.
+type EventInvite struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	EventID   uint       `gorm:"index" json:"event_id"`
+	Token     string     `gorm:"uniqueIndex" json:"token"`
+	Email     *string    `gorm:"index" json:"email,omitempty"`
+	Role      string     `gorm:"default:member" json:"role"`
+	GuestID   *uint      `json:"guest_id,omitempty"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	Status    string     `gorm:"default:pending" json:"status"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedBy uint       `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (i EventInvite) Expired(now time.Time) bool {
+	return i.ExpiresAt != nil && now.After(*i.ExpiresAt)
+}
+
+// Статусы регулярной траты.
+const (
+	RecurringActive = "active"
+	RecurringPaused = "paused"
+	RecurringEnded  = "ended"
+)
+
+// RecurringExpense — шаблон траты, которая повторяется по расписанию Schedule
+// (RRULE, см. пакет rrule). NextRunAt — ближайшее ещё не созданное повторение.
+type RecurringExpense struct {
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	EventID     uint        `gorm:"index" json:"event_id"`
+	Title       string      `json:"title"`
+	Amount      money.Money `json:"amount"`
+	PaidBy      uint        `json:"paid_by"`
+	SplitMode   string      `json:"split_mode"`
+	Schedule    string      `json:"schedule"`
+	StartsAt    time.Time   `json:"starts_at"`
+	EndsAt      *time.Time  `json:"ends_at"`
+	NextRunAt   *time.Time  `gorm:"index" json:"next_run_at"`
+	Status      string      `gorm:"default:active" json:"status"`
+	Occurrences int         `json:"occurrences"`
+	CreatedBy   uint        `json:"created_by"`
+	CreatedAt   time.Time   `json:"created_at"`
+
+	Shares []RecurringExpenseShare `gorm:"foreignKey:RecurringExpenseID" json:"shares,omitempty"`
+}
+
+type RecurringExpenseShare struct {
+	ID                 uint          `gorm:"primaryKey" json:"id"`
+	RecurringExpenseID uint          `gorm:"index" json:"recurring_expense_id"`
+	UserID             uint          `json:"user_id"`
+	ShareAmount        money.Money   `json:"share_amount"`
+	Percent            money.Percent `json:"percent,omitempty"`
+	Weight             int64         `json:"weight,omitempty"`
 }
@@ -0,0 +1,94 @@
+package money
+
+import "strings"
+
+// Currency — трёхбуквенный код валюты ISO 4217.
+type Currency string
+
+const DefaultCurrency Currency = "RUB"
+
+var currencySymbols = map[Currency]string{
+	"RUB": "₽",
+	"USD": "$",
+	"EUR": "€",
+	"GBP": "£",
+	"KZT": "₸",
+	"UAH": "₴",
+	"BYN": "Br",
+	"GEL": "₾",
+	"AMD": "֏",
+	"TRY": "₺",
+	"CNY": "¥",
+}
+
+// isoCurrencies — действующие коды валют ISO 4217 с двумя знаками после
+// запятой, без фондов, драгметаллов и служебных кодов. Money хранит суммы
+// в сотых долях (Scale = 2), поэтому валюты без копеек (JPY, KRW) и с тремя
+// знаками (KWD, BHD) не поддерживаются.
+var isoCurrencies = func() map[Currency]bool {
+	codes := []Currency{
+		"AED", "AFN", "ALL", "AMD", "ANG", "AOA", "ARS", "AUD", "AWG", "AZN", "BAM", "BBD",
+		"BDT", "BGN", "BMD", "BND", "BOB", "BRL", "BSD", "BTN", "BWP", "BYN", "BZD", "CAD",
+		"CDF", "CHF", "CNY", "COP", "CRC", "CUC", "CUP", "CVE", "CZK", "DKK", "DOP", "DZD",
+		"EGP", "ERN", "ETB", "EUR", "FJD", "FKP", "GBP", "GEL", "GHS", "GIP", "GMD", "GTQ",
+		"GYD", "HKD", "HNL", "HTG", "HUF", "IDR", "ILS", "INR", "IRR", "JMD", "KES", "KGS",
+		"KHR", "KPW", "KYD", "KZT", "LAK", "LBP", "LKR", "LRD", "LSL", "MAD", "MDL", "MGA",
+		"MKD", "MMK", "MNT", "MOP", "MRU", "MUR", "MVR", "MWK", "MXN", "MYR", "MZN", "NAD",
+		"NGN", "NIO", "NOK", "NPR", "NZD", "PAB", "PEN", "PGK", "PHP", "PKR", "PLN", "QAR",
+		"RON", "RSD", "RUB", "SAR", "SBD", "SCR", "SDG", "SEK", "SGD", "SHP", "SLE", "SLL",
+		"SOS", "SRD", "SSP", "STN", "SVC", "SYP", "SZL", "THB", "TJS", "TMT", "TOP", "TRY",
+		"TTD", "TWD", "TZS", "UAH", "USD", "UYU", "UZS", "VED", "VES", "WST", "XCD", "XCG",
+		"YER", "ZAR", "ZMW", "ZWG", "ZWL",
+	}
+	m := make(map[Currency]bool, len(codes))
+	for _, c := range codes {
+		m[c] = true
+	}
+	return m
+}()
+
+// Valid сообщает, что c — поддерживаемый код валюты ISO 4217.
+func (c Currency) Valid() bool {
+	return isoCurrencies[c]
+}
+
+// Symbol возвращает знак валюты, а для валют без известного знака — её код.
+func (c Currency) Symbol() string {
+	if s, ok := currencySymbols[c]; ok {
+		return s
+	}
+	return string(c)
+}
+
+// ParseCurrency узнаёт валюту по коду ISO 4217 или по знаку: "RUB", "₽".
+func ParseCurrency(s string) (Currency, bool) {
+	if c := Currency(s); c.Valid() {
+		return c, true
+	}
+	for c, symbol := range currencySymbols {
+		if s == symbol {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// Format записывает сумму для людей: разряды разделены неразрывным
+// пробелом, знак валюты — после числа: "2 450.00 ₽".
+func (m Money) Format(c Currency) string {
+	s := m.String()
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac, _ := strings.Cut(s, ".")
+
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(" ")
+		}
+		b.WriteRune(r)
+	}
+	return sign + b.String() + "." + frac + " " + c.Symbol()
+}
@@ -0,0 +1,81 @@
+package money
+
+import "testing"
+
+func TestCurrencyValid(t *testing.T) {
+	tests := []struct {
+		c    Currency
+		want bool
+	}{
+		{"RUB", true},
+		{"EUR", true},
+		{"KZT", true},
+		{"ZWG", true},
+		// Суммы хранятся в сотых долях: валюты без копеек и с тремя знаками
+		// после запятой посчитались бы в 100 и в 10 раз неверно.
+		{"JPY", false},
+		{"KRW", false},
+		{"KWD", false},
+		{"BHD", false},
+		{"ABC", false},
+		{"XXX", false},
+		{"XAU", false},
+		{"rub", false},
+		{"RU", false},
+		{"RUBL", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := tt.c.Valid(); got != tt.want {
+			t.Errorf("Currency(%q).Valid() = %v, want %v", tt.c, got, tt.want)
+		}
+	}
+}
+
+func TestParseCurrency(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Currency
+		ok   bool
+	}{
+		{"RUB", "RUB", true},
+		{"₽", "RUB", true},
+		{"€", "EUR", true},
+		{"Br", "BYN", true},
+		{"CHF", "CHF", true},
+		{"JPY", "", false},
+		{"QQQ", "", false},
+		{"euro", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseCurrency(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseCurrency(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// Разряды и валюта отделены неразрывным пробелом U+00A0.
+func TestMoneyFormat(t *testing.T) {
+	tests := []struct {
+		m    Money
+		c    Currency
+		want string
+	}{
+		{0, "RUB", "0.00\u00a0₽"},
+		{5, "RUB", "0.05\u00a0₽"},
+		{99999, "RUB", "999.99\u00a0₽"},
+		{100000, "RUB", "1\u00a0000.00\u00a0₽"},
+		{245000, "RUB", "2\u00a0450.00\u00a0₽"},
+		{123456789, "USD", "1\u00a0234\u00a0567.89\u00a0$"},
+		{-245050, "EUR", "-2\u00a0450.50\u00a0€"},
+		{-5, "EUR", "-0.05\u00a0€"},
+		{1250, "CHF", "12.50\u00a0CHF"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Format(tt.c); got != tt.want {
+			t.Errorf("Money(%d).Format(%q) = %q, want %q", tt.m, tt.c, got, tt.want)
+		}
+	}
+}
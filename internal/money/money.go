@@ -0,0 +1,152 @@
+// Package money хранит денежные суммы в целых минимальных единицах (копейках),
+// чтобы расчёты долей и долгов сходились до копейки без ошибок float64.
+package money
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Scale — количество знаков после запятой у денежной суммы.
+const Scale = 2
+
+// Money — сумма в минимальных единицах валюты (1 = 0.01).
+type Money int64
+
+var ErrInvalid = errors.New("некорректная сумма")
+
+// FromMinor создаёт сумму из минимальных единиц.
+func FromMinor(v int64) Money {
+	return Money(v)
+}
+
+// Parse разбирает десятичную запись вида "100", "100.5", "-3.07" без потери точности.
+func Parse(s string) (Money, error) {
+	v, err := ParseDecimal(s, Scale)
+	if err != nil {
+		return 0, err
+	}
+	return Money(v), nil
+}
+
+func (m Money) Minor() int64 {
+	return int64(m)
+}
+
+func (m Money) String() string {
+	return FormatDecimal(int64(m), Scale)
+}
+
+func (m Money) IsZero() bool {
+	return m == 0
+}
+
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON принимает как число (100.50), так и строку ("100.50").
+func (m *Money) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalDecimal(data, Scale)
+	if err != nil {
+		return err
+	}
+	*m = Money(v)
+	return nil
+}
+
+// ParseDecimal переводит десятичную строку в целое число с заданным количеством знаков
+// после запятой. Лишние значащие знаки считаются ошибкой, а не округляются.
+func ParseDecimal(s string, scale int) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("%w: пустое значение", ErrInvalid)
+	}
+
+	neg := false
+	switch s[0] {
+	case '-':
+		neg = true
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+
+	intPart, fracPart, hasDot := strings.Cut(s, ".")
+	if intPart == "" && (!hasDot || fracPart == "") {
+		return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+	}
+	fracPart = strings.TrimRight(fracPart, "0")
+	if len(fracPart) > scale {
+		return 0, fmt.Errorf("%w: больше %d знаков после запятой", ErrInvalid, scale)
+	}
+	fracPart += strings.Repeat("0", scale-len(fracPart))
+	if intPart == "" {
+		intPart = "0"
+	}
+
+	for _, r := range intPart + fracPart {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+	}
+
+	v, err := strconv.ParseInt(intPart+fracPart, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+// FormatDecimal — обратная операция к ParseDecimal.
+func FormatDecimal(v int64, scale int) string {
+	sign := ""
+	u := uint64(v)
+	if v < 0 {
+		sign = "-"
+		u = uint64(-v)
+		if v == math.MinInt64 {
+			u = uint64(math.MaxInt64) + 1
+		}
+	}
+	digits := strconv.FormatUint(u, 10)
+	if scale == 0 {
+		return sign + digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+func unmarshalDecimal(data []byte, scale int) (int64, error) {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return 0, nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		s, err := strconv.Unquote(string(data))
+		if err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		return ParseDecimal(s, scale)
+	}
+	if bytes.ContainsAny(data, "eE") {
+		return 0, fmt.Errorf("%w: экспоненциальная запись не поддерживается", ErrInvalid)
+	}
+	return ParseDecimal(string(data), scale)
+}
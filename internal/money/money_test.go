@@ -0,0 +1,138 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+	}{
+		{"0", 0},
+		{"100", 10000},
+		{"100.5", 10050},
+		{"100.50", 10050},
+		{"100.500", 10050},
+		{"-3.07", -307},
+		{"+3.07", 307},
+		{".5", 50},
+		{"5.", 500},
+		{"0.01", 1},
+		{"  42.10 ", 4210},
+		{"92233720368547758.07", math.MaxInt64},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"-",
+		".",
+		"abc",
+		"1,5",
+		"1.005", // лишняя значащая цифра не округляется
+		"1.2.3",
+		"--1",
+		"1e3",
+		"0x10",
+		"92233720368547758.08",
+	} {
+		if got, err := Parse(in); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Parse(%q) = %d, %v; want ErrInvalid", in, got, err)
+		}
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	tests := []struct {
+		v     int64
+		scale int
+		want  string
+	}{
+		{0, 2, "0.00"},
+		{1, 2, "0.01"},
+		{-1, 2, "-0.01"},
+		{10050, 2, "100.50"},
+		{-307, 2, "-3.07"},
+		{42, 0, "42"},
+		{math.MinInt64, 2, "-92233720368547758.08"},
+	}
+	for _, tt := range tests {
+		if got := FormatDecimal(tt.v, tt.scale); got != tt.want {
+			t.Errorf("FormatDecimal(%d, %d) = %q, want %q", tt.v, tt.scale, got, tt.want)
+		}
+	}
+}
+
+func TestParseFormatRoundTrip(t *testing.T) {
+	for _, v := range []Money{0, 1, -1, 99, 100, 123456789, -987654321, math.MaxInt64} {
+		got, err := Parse(v.String())
+		if err != nil || got != v {
+			t.Errorf("Parse(%q) = %d, %v; want %d", v.String(), got, err, v)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var v struct {
+		A Money `json:"a"`
+		B Money `json:"b"`
+		C Money `json:"c"`
+	}
+	if err := json.Unmarshal([]byte(`{"a": 100.5, "b": "2.07", "c": null}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A != 10050 || v.B != 207 || v.C != 0 {
+		t.Errorf("Unmarshal = %+v", v)
+	}
+
+	out, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"a":100.50,"b":2.07,"c":0.00}` {
+		t.Errorf("Marshal = %s", out)
+	}
+
+	for _, in := range []string{`{"a": 1e2}`, `{"a": 0.001}`, `{"a": "x"}`, `{"a": true}`} {
+		if err := json.Unmarshal([]byte(in), &v); err == nil {
+			t.Errorf("Unmarshal(%s) succeeded", in)
+		}
+	}
+}
+
+func TestPercentOf(t *testing.T) {
+	tests := []struct {
+		p    Percent
+		m    Money
+		want Money
+	}{
+		{Hundred, 12345, 12345},
+		{1000, 10000, 1000}, // 10% от 100.00
+		{1250, 1000, 125},   // 12.5% от 10.00
+		{1000, 5, 1},        // 0.5 копейки округляется вверх
+		{1000, 4, 0},        // 0.4 копейки округляется вниз
+		{1000, -5, -1},      // и от нуля для отрицательных
+		{3333, 100, 33},     // 33.33% от 1.00
+		{0, 100000, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.p.Of(tt.m); got != tt.want {
+			t.Errorf("%s%% of %s = %s, want %s", tt.p, tt.m, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package money
+
+// PercentScale — проценты хранятся в сотых долях процента (33.33% = 3333).
+const PercentScale = 2
+
+// Hundred — 100% в единицах Percent.
+const Hundred Percent = 100_00
+
+type Percent int64
+
+func ParsePercent(s string) (Percent, error) {
+	v, err := ParseDecimal(s, PercentScale)
+	if err != nil {
+		return 0, err
+	}
+	return Percent(v), nil
+}
+
+func (p Percent) String() string {
+	return FormatDecimal(int64(p), PercentScale)
+}
+
+func (p Percent) MarshalJSON() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Percent) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalDecimal(data, PercentScale)
+	if err != nil {
+		return err
+	}
+	*p = Percent(v)
+	return nil
+}
+
+// Of возвращает p процентов от m с округлением половины копейки от нуля.
+func (p Percent) Of(m Money) Money {
+	v := int64(m) * int64(p)
+	q, r := v/int64(Hundred), v%int64(Hundred)
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= int64(Hundred) {
+		if v < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Money(q)
+}
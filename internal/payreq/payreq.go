@@ -0,0 +1,198 @@
+// Package payreq формирует платёжные запросы для QR-кодов: строку по ГОСТ Р
+// 56042-2014 (ST00012), которую понимают приложения российских банков, и
+// EPC QR (SEPA Credit Transfer) для переводов в евро.
+package payreq
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"split-the-bill/internal/money"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalid = errors.New("некорректные реквизиты")
+	ErrMissing = errors.New("не хватает реквизитов")
+)
+
+// Форматы платёжного запроса.
+const (
+	FormatST00012 = "st00012"
+	FormatEPC     = "epc"
+)
+
+// Payee — реквизиты получателя. Для ST00012 нужны Name, BankName, BIC,
+// Account и CorrAccount, для EPC — Name и IBAN (SWIFT необязателен).
+type Payee struct {
+	Name        string
+	BankName    string
+	BIC         string
+	Account     string
+	CorrAccount string
+	INN         string
+	IBAN        string
+	SWIFT       string
+}
+
+// Validate проверяет формат заполненных реквизитов; пустые поля не проверяются.
+func (p Payee) Validate() error {
+	for _, f := range []struct {
+		name, value string
+	}{{"name", p.Name}, {"bank_name", p.BankName}} {
+		if strings.ContainsAny(f.value, "|\r\n") {
+			return fmt.Errorf("%w: %s содержит недопустимые символы", ErrInvalid, f.name)
+		}
+	}
+	switch {
+	case p.BIC != "" && !digits(p.BIC, 9):
+		return fmt.Errorf("%w: БИК — 9 цифр", ErrInvalid)
+	case p.Account != "" && !digits(p.Account, 20):
+		return fmt.Errorf("%w: номер счёта — 20 цифр", ErrInvalid)
+	case p.CorrAccount != "" && !digits(p.CorrAccount, 20):
+		return fmt.Errorf("%w: корреспондентский счёт — 20 цифр", ErrInvalid)
+	case p.INN != "" && !digits(p.INN, 10) && !digits(p.INN, 12):
+		return fmt.Errorf("%w: ИНН — 10 или 12 цифр", ErrInvalid)
+	case p.IBAN != "" && !validIBAN(p.IBAN):
+		return fmt.Errorf("%w: IBAN", ErrInvalid)
+	case p.SWIFT != "" && !validSWIFT(p.SWIFT):
+		return fmt.Errorf("%w: SWIFT/BIC", ErrInvalid)
+	}
+	return nil
+}
+
+// ST00012 собирает строку платёжного запроса по ГОСТ Р 56042-2014 в UTF-8.
+// Sum указывается в копейках.
+func ST00012(p Payee, amount money.Money, purpose string) (string, error) {
+	if p.Name == "" || p.BankName == "" || p.BIC == "" || p.Account == "" || p.CorrAccount == "" {
+		return "", fmt.Errorf("%w: для ST00012 нужны имя, банк, БИК, счёт и корреспондентский счёт", ErrMissing)
+	}
+	if amount <= 0 {
+		return "", fmt.Errorf("%w: сумма должна быть положительной", ErrInvalid)
+	}
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	fields := []string{
+		"ST00012",
+		"Name=" + p.Name,
+		"PersonalAcc=" + p.Account,
+		"BankName=" + p.BankName,
+		"BIC=" + p.BIC,
+		"CorrespAcc=" + p.CorrAccount,
+		fmt.Sprintf("Sum=%d", amount.Minor()),
+	}
+	if purpose = clean(purpose, "|"); purpose != "" {
+		fields = append(fields, "Purpose="+truncate(purpose, 210))
+	}
+	if p.INN != "" {
+		fields = append(fields, "PayeeINN="+p.INN)
+	}
+	return strings.Join(fields, "|"), nil
+}
+
+// EPC собирает EPC QR версии 002 для перевода в евро.
+func EPC(p Payee, amount money.Money, currency money.Currency, remittance string) (string, error) {
+	if p.Name == "" || p.IBAN == "" {
+		return "", fmt.Errorf("%w: для EPC QR нужны имя и IBAN", ErrMissing)
+	}
+	if currency != "EUR" {
+		return "", fmt.Errorf("%w: EPC QR поддерживает только EUR", ErrInvalid)
+	}
+	if amount <= 0 || amount > 99999999999 {
+		return "", fmt.Errorf("%w: сумма должна быть от 0.01 до 999999999.99", ErrInvalid)
+	}
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	lines := []string{
+		"BCD",
+		"002",
+		"1", // UTF-8
+		"SCT",
+		strings.ToUpper(p.SWIFT),
+		truncate(p.Name, 70),
+		compact(p.IBAN),
+		"EUR" + amount.String(),
+		"", // код назначения
+		"", // структурированная ссылка
+		truncate(clean(remittance, "\r\n"), 140),
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n"), nil
+}
+
+func digits(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// validIBAN проверяет длину, код страны и контрольную сумму по модулю 97.
+func validIBAN(s string) bool {
+	s = compact(s)
+	if len(s) < 15 || len(s) > 34 {
+		return false
+	}
+	var b strings.Builder
+	for _, r := range s[4:] + s[:4] {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r >= 'A' && r <= 'Z':
+			fmt.Fprintf(&b, "%d", r-'A'+10)
+		default:
+			return false
+		}
+	}
+	if s[0] < 'A' || s[0] > 'Z' || s[1] < 'A' || s[1] > 'Z' {
+		return false
+	}
+	n, ok := new(big.Int).SetString(b.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+func validSWIFT(s string) bool {
+	s = strings.ToUpper(s)
+	if len(s) != 8 && len(s) != 11 {
+		return false
+	}
+	for i, r := range s {
+		letter := r >= 'A' && r <= 'Z'
+		if i < 6 && !letter || i >= 6 && !letter && (r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// compact убирает пробелы из IBAN и приводит его к верхнему регистру.
+func compact(s string) string {
+	return strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+}
+
+// clean заменяет пробелами символы, недопустимые в значении поля.
+func clean(s, chars string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(chars, r) {
+			return ' '
+		}
+		return r
+	}, s))
+}
+
+// truncate обрезает s до n символов.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
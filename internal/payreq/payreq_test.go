@@ -0,0 +1,250 @@
+package payreq
+
+import (
+	"errors"
+	"split-the-bill/internal/money"
+	"strings"
+	"testing"
+)
+
+var (
+	rubPayee = Payee{
+		Name:        "Иван Петров",
+		BankName:    "ПАО Сбербанк",
+		BIC:         "044525225",
+		Account:     "40817810099910004312",
+		CorrAccount: "30101810400000000225",
+	}
+	eurPayee = Payee{
+		Name:  "Anna Schmidt",
+		IBAN:  "de89 3704 0044 0532 0130 00",
+		SWIFT: "cobadeffxxx",
+	}
+)
+
+func TestST00012(t *testing.T) {
+	withINN := rubPayee
+	withINN.INN = "7707083893"
+
+	tests := []struct {
+		name    string
+		payee   Payee
+		amount  money.Money
+		purpose string
+		want    string
+	}{
+		{
+			name:    "with purpose",
+			payee:   rubPayee,
+			amount:  245050,
+			purpose: "Долг за ужин",
+			want: "ST00012|Name=Иван Петров|PersonalAcc=40817810099910004312|BankName=ПАО Сбербанк" +
+				"|BIC=044525225|CorrespAcc=30101810400000000225|Sum=245050|Purpose=Долг за ужин",
+		},
+		{
+			name:   "without purpose",
+			payee:  rubPayee,
+			amount: 100,
+			want: "ST00012|Name=Иван Петров|PersonalAcc=40817810099910004312|BankName=ПАО Сбербанк" +
+				"|BIC=044525225|CorrespAcc=30101810400000000225|Sum=100",
+		},
+		{
+			name:    "separator in purpose",
+			payee:   withINN,
+			amount:  5000,
+			purpose: " Такси|Кино ",
+			want: "ST00012|Name=Иван Петров|PersonalAcc=40817810099910004312|BankName=ПАО Сбербанк" +
+				"|BIC=044525225|CorrespAcc=30101810400000000225|Sum=5000|Purpose=Такси Кино|PayeeINN=7707083893",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ST00012(tt.payee, tt.amount, tt.purpose)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("ST00012() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestST00012TruncatesPurpose(t *testing.T) {
+	got, err := ST00012(rubPayee, 100, strings.Repeat("я", 300))
+	if err != nil {
+		t.Fatal(err)
+	}
+	purpose := got[strings.Index(got, "Purpose=")+len("Purpose="):]
+	if purpose != strings.Repeat("я", 210) {
+		t.Errorf("purpose has %d runes, want 210", len([]rune(purpose)))
+	}
+}
+
+func TestST00012Invalid(t *testing.T) {
+	noBank := rubPayee
+	noBank.BankName = ""
+	badBIC := rubPayee
+	badBIC.BIC = "04452522"
+	pipeName := rubPayee
+	pipeName.Name = "Иван|Sum=1"
+
+	tests := []struct {
+		name   string
+		payee  Payee
+		amount money.Money
+		want   error
+	}{
+		{"missing bank", noBank, 100, ErrMissing},
+		{"short BIC", badBIC, 100, ErrInvalid},
+		{"separator in name", pipeName, 100, ErrInvalid},
+		{"zero amount", rubPayee, 0, ErrInvalid},
+		{"negative amount", rubPayee, -100, ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := ST00012(tt.payee, tt.amount, ""); !errors.Is(err, tt.want) {
+				t.Errorf("ST00012() = %q, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEPC(t *testing.T) {
+	noSWIFT := eurPayee
+	noSWIFT.SWIFT = ""
+
+	tests := []struct {
+		name       string
+		payee      Payee
+		amount     money.Money
+		remittance string
+		want       string
+	}{
+		{
+			name:       "full",
+			payee:      eurPayee,
+			amount:     1234,
+			remittance: "Dinner\r\nin Berlin",
+			want:       "BCD\n002\n1\nSCT\nCOBADEFFXXX\nAnna Schmidt\nDE89370400440532013000\nEUR12.34\n\n\nDinner  in Berlin",
+		},
+		{
+			name:   "without swift and remittance",
+			payee:  noSWIFT,
+			amount: 100000,
+			want:   "BCD\n002\n1\nSCT\n\nAnna Schmidt\nDE89370400440532013000\nEUR1000.00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EPC(tt.payee, tt.amount, "EUR", tt.remittance)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("EPC() =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEPCInvalid(t *testing.T) {
+	badIBAN := eurPayee
+	badIBAN.IBAN = "DE89370400440532013001"
+	noIBAN := eurPayee
+	noIBAN.IBAN = ""
+
+	tests := []struct {
+		name     string
+		payee    Payee
+		amount   money.Money
+		currency string
+		want     error
+	}{
+		{"bad checksum", badIBAN, 100, "EUR", ErrInvalid},
+		{"missing IBAN", noIBAN, 100, "EUR", ErrMissing},
+		{"not euro", eurPayee, 100, "RUB", ErrInvalid},
+		{"zero amount", eurPayee, 0, "EUR", ErrInvalid},
+		{"too large", eurPayee, 100000000000, "EUR", ErrInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := EPC(tt.payee, tt.amount, money.Currency(tt.currency), ""); !errors.Is(err, tt.want) {
+				t.Errorf("EPC() = %q, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidIBAN(t *testing.T) {
+	tests := []struct {
+		iban string
+		want bool
+	}{
+		{"DE89370400440532013000", true},
+		{"de89 3704 0044 0532 0130 00", true},
+		{"GB82WEST12345698765432", true},
+		{"FR1420041010050500013M02606", true},
+		{"NL91ABNA0417164300", true},
+		{"DE89370400440532013001", false},
+		{"GB82WEST12345698765431", false},
+		{"DE8937040044", false},
+		{"1289370400440532013000", false},
+		{"DE89-3704-0044-0532-0130-00", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := validIBAN(tt.iban); got != tt.want {
+			t.Errorf("validIBAN(%q) = %v, want %v", tt.iban, got, tt.want)
+		}
+	}
+}
+
+func TestValidSWIFT(t *testing.T) {
+	tests := []struct {
+		swift string
+		want  bool
+	}{
+		{"COBADEFF", true},
+		{"COBADEFFXXX", true},
+		{"deutdeff500", true},
+		{"SABRRUMM", true},
+		{"COBADEF", false},
+		{"COBADEFFXX", false},
+		{"C0BADEFF", false},
+		{"COBADE-F", false},
+	}
+	for _, tt := range tests {
+		if got := validSWIFT(tt.swift); got != tt.want {
+			t.Errorf("validSWIFT(%q) = %v, want %v", tt.swift, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		payee Payee
+		ok    bool
+	}{
+		{"empty", Payee{}, true},
+		{"rub", rubPayee, true},
+		{"eur", eurPayee, true},
+		{"inn 12 digits", Payee{INN: "500100732259"}, true},
+		{"inn 11 digits", Payee{INN: "50010073225"}, false},
+		{"account letters", Payee{Account: "4081781009991000431A"}, false},
+		{"corr account short", Payee{CorrAccount: "3010181040000000022"}, false},
+		{"newline in bank", Payee{BankName: "Банк\nSum=1"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.payee.Validate()
+			if tt.ok && err != nil {
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrInvalid) {
+				t.Errorf("Validate() = %v, want ErrInvalid", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package payreq
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/skip2/go-qrcode"
+)
+
+// PNG рисует QR-код со строкой payload размером size×size пикселей.
+func PNG(payload string, size int) ([]byte, error) {
+	return qrcode.Encode(payload, qrcode.Medium, size)
+}
+
+// SVG рисует QR-код со строкой payload как векторное изображение: каждый тёмный
+// модуль — квадрат 1×1, размер задаёт viewBox, поэтому картинка масштабируется без потерь.
+func SVG(payload string) ([]byte, error) {
+	q, err := qrcode.New(payload, qrcode.Medium)
+	if err != nil {
+		return nil, err
+	}
+	bitmap := q.Bitmap()
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, len(bitmap), len(bitmap))
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, len(bitmap), len(bitmap))
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&b, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.Bytes(), nil
+}
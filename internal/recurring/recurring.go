@@ -0,0 +1,68 @@
+// Package recurring превращает наступившие повторения регулярных трат в
+// обычные траты. Саму трату создаёт переданная CreateFunc, поэтому пакет не
+// зависит от HTTP-обработчиков и используется и ими, и планировщиком.
+package recurring
+
+import (
+	"gorm.io/gorm"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/rrule"
+	"time"
+)
+
+// MaxCatchUp ограничивает число повторений, создаваемых за один проход,
+// чтобы шаблон с частым расписанием после долгого простоя не занял транзакцию надолго.
+const MaxCatchUp = 100
+
+// CreateFunc создаёт в событии трату-повторение шаблона rec на момент occurrence.
+type CreateFunc func(tx *gorm.DB, event models.Event, rec models.RecurringExpense, occurrence time.Time) error
+
+// Materialize создаёт траты для всех наступивших к now повторений шаблона
+// и сохраняет сдвинутый NextRunAt. Вызывать в транзакции, заблокировав строку
+// шаблона: тогда повторный вызов с тем же now ничего не создаст.
+func Materialize(tx *gorm.DB, rec *models.RecurringExpense, now time.Time, create CreateFunc) (int, error) {
+	rule, err := rrule.Parse(rec.Schedule)
+	if err != nil {
+		return 0, err
+	}
+
+	var event models.Event
+	if err := tx.First(&event, rec.EventID).Error; err != nil {
+		return 0, err
+	}
+
+	template := *rec
+	due := Due(rec, rule, now)
+	for i, occurrence := range due {
+		if err := create(tx, event, template, occurrence); err != nil {
+			return i, err
+		}
+	}
+	return len(due), tx.Omit("Shares").Save(rec).Error
+}
+
+// Due возвращает наступившие к now повторения шаблона (не больше MaxCatchUp)
+// и сдвигает NextRunAt, Occurrences и Status так, будто они уже созданы.
+func Due(rec *models.RecurringExpense, rule rrule.Rule, now time.Time) []time.Time {
+	var due []time.Time
+	for rec.Status == models.RecurringActive && rec.NextRunAt != nil &&
+		!rec.NextRunAt.After(now) && len(due) < MaxCatchUp {
+		occurrence := *rec.NextRunAt
+		due = append(due, occurrence)
+		rec.Occurrences++
+		Advance(rec, rule, occurrence)
+	}
+	return due
+}
+
+// Advance переносит NextRunAt на первое повторение после after
+// или завершает шаблон, если повторений больше нет.
+func Advance(rec *models.RecurringExpense, rule rrule.Rule, after time.Time) {
+	next, ok := rule.Next(rec.StartsAt, after)
+	if !ok || (rec.EndsAt != nil && next.After(*rec.EndsAt)) {
+		rec.Status = models.RecurringEnded
+		rec.NextRunAt = nil
+		return
+	}
+	rec.NextRunAt = &next
+}
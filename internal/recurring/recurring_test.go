@@ -0,0 +1,153 @@
+package recurring
+
+import (
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/rrule"
+	"testing"
+	"time"
+)
+
+func template(t *testing.T, schedule string, start time.Time) (*models.RecurringExpense, rrule.Rule) {
+	t.Helper()
+	rule, err := rrule.Parse(schedule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := start
+	return &models.RecurringExpense{
+		Schedule:  schedule,
+		StartsAt:  start,
+		NextRunAt: &next,
+		Status:    models.RecurringActive,
+	}, rule
+}
+
+func day(m time.Month, d int) time.Time {
+	return time.Date(2026, m, d, 12, 0, 0, 0, time.UTC)
+}
+
+func TestDue(t *testing.T) {
+	tests := []struct {
+		name       string
+		schedule   string
+		start, now time.Time
+		endsAt     *time.Time
+		want       []time.Time
+		wantNext   *time.Time
+		wantStatus string
+	}{
+		{
+			name:       "nothing due yet",
+			schedule:   "FREQ=MONTHLY;BYMONTHDAY=1",
+			start:      day(3, 1),
+			now:        day(2, 20),
+			wantNext:   ptr(day(3, 1)),
+			wantStatus: models.RecurringActive,
+		},
+		{
+			name:       "due exactly now",
+			schedule:   "FREQ=MONTHLY;BYMONTHDAY=1",
+			start:      day(3, 1),
+			now:        day(3, 1),
+			want:       []time.Time{day(3, 1)},
+			wantNext:   ptr(day(4, 1)),
+			wantStatus: models.RecurringActive,
+		},
+		{
+			name:       "catches up missed occurrences",
+			schedule:   "FREQ=WEEKLY",
+			start:      day(1, 5),
+			now:        day(1, 27),
+			want:       []time.Time{day(1, 5), day(1, 12), day(1, 19), day(1, 26)},
+			wantNext:   ptr(day(2, 2)),
+			wantStatus: models.RecurringActive,
+		},
+		{
+			name:       "count ends the template",
+			schedule:   "FREQ=DAILY;COUNT=2",
+			start:      day(1, 1),
+			now:        day(1, 10),
+			want:       []time.Time{day(1, 1), day(1, 2)},
+			wantStatus: models.RecurringEnded,
+		},
+		{
+			name:       "ends_at ends the template",
+			schedule:   "FREQ=DAILY",
+			start:      day(1, 1),
+			now:        day(1, 10),
+			endsAt:     ptr(day(1, 3)),
+			want:       []time.Time{day(1, 1), day(1, 2), day(1, 3)},
+			wantStatus: models.RecurringEnded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, rule := template(t, tt.schedule, tt.start)
+			rec.EndsAt = tt.endsAt
+
+			got := Due(rec, rule, tt.now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Due = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+			if rec.Occurrences != len(tt.want) {
+				t.Errorf("Occurrences = %d, want %d", rec.Occurrences, len(tt.want))
+			}
+			if rec.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", rec.Status, tt.wantStatus)
+			}
+			switch {
+			case tt.wantNext == nil && rec.NextRunAt != nil:
+				t.Errorf("NextRunAt = %v, want nil", *rec.NextRunAt)
+			case tt.wantNext != nil && (rec.NextRunAt == nil || !rec.NextRunAt.Equal(*tt.wantNext)):
+				t.Errorf("NextRunAt = %v, want %v", rec.NextRunAt, *tt.wantNext)
+			}
+
+			// Повторный проход с тем же now ничего не создаёт.
+			if again := Due(rec, rule, tt.now); len(again) != 0 {
+				t.Errorf("second Due = %v, want none", again)
+			}
+		})
+	}
+}
+
+func TestDueLimitsCatchUp(t *testing.T) {
+	rec, rule := template(t, "FREQ=DAILY", day(1, 1))
+	now := day(12, 31)
+
+	first := Due(rec, rule, now)
+	if len(first) != MaxCatchUp {
+		t.Fatalf("Due created %d, want %d", len(first), MaxCatchUp)
+	}
+	// Следующий проход продолжает с того же места, без пропусков и повторов.
+	second := Due(rec, rule, now)
+	if len(second) == 0 || !second[0].Equal(first[len(first)-1].AddDate(0, 0, 1)) {
+		t.Fatalf("second pass starts at %v, want day after %v", second, first[len(first)-1])
+	}
+}
+
+func TestDueSkipsInactive(t *testing.T) {
+	for _, status := range []string{models.RecurringPaused, models.RecurringEnded} {
+		rec, rule := template(t, "FREQ=DAILY", day(1, 1))
+		rec.Status = status
+		if got := Due(rec, rule, day(2, 1)); len(got) != 0 {
+			t.Errorf("%s template: Due = %v, want none", status, got)
+		}
+	}
+}
+
+func TestAdvanceSkipsOccurrence(t *testing.T) {
+	rec, rule := template(t, "FREQ=MONTHLY;BYMONTHDAY=-1", day(1, 31))
+	Advance(rec, rule, *rec.NextRunAt)
+	if rec.NextRunAt == nil || !rec.NextRunAt.Equal(day(2, 28)) {
+		t.Fatalf("NextRunAt = %v, want %v", rec.NextRunAt, day(2, 28))
+	}
+}
+
+func ptr(t time.Time) *time.Time {
+	return &t
+}
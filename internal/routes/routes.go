@@ -4,30 +4,82 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 	"split-the-bill/internal/controllers"
+	"split-the-bill/internal/middleware"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/storage"
 )
 
-func SetupRoutes(r *gin.RouterGroup, db *gorm.DB) {
+func SetupRoutes(r *gin.RouterGroup, db *gorm.DB, store storage.Storage) {
 	r.POST("/users", controllers.CreateUser(db))
 	r.GET("/users", controllers.ListUsers(db))
 
 	r.POST("/events", controllers.CreateEvent(db))
 	r.GET("/events", controllers.GetEvents(db))
-	r.POST("/events/:id/participants", controllers.AddParticipant(db))
-	r.GET("/events/:id/participants", controllers.ListParticipants(db))
 
-	r.POST("/events/:id/expenses", func(c *gin.Context) {
+	admin := middleware.RequireRole(models.RoleAdmin)
+	member := middleware.RequireRole(models.RoleMember)
+
+	event := r.Group("/events/:id", middleware.EventMember(db))
+	event.PUT("", admin, controllers.UpdateEvent(db))
+	event.POST("/participants", admin, controllers.AddParticipant(db))
+	event.GET("/participants", controllers.ListParticipants(db))
+	event.DELETE("/participants/:user_id", admin, controllers.RemoveParticipant(db))
+	event.PUT("/participants/:user_id/role", admin, controllers.UpdateParticipantRole(db))
+	event.POST("/transfer-ownership", middleware.RequireRole(models.RoleOwner), controllers.TransferOwnership(db))
+	event.POST("/guests", member, controllers.AddGuest(db))
+	event.GET("/guests", controllers.ListGuests(db))
+	event.POST("/guests/:user_id/claim", admin, controllers.ClaimGuest(db))
+	event.POST("/invites", admin, controllers.CreateInvite(db))
+	event.GET("/invites", admin, controllers.ListInvites(db))
+	event.DELETE("/invites/:invite_id", admin, controllers.RevokeInvite(db))
+
+	r.GET("/invites", controllers.ListMyInvites(db))
+	r.GET("/invites/:token", controllers.GetInvite(db))
+	r.POST("/invites/:token/accept", controllers.AcceptInvite(db))
+	r.POST("/invites/:token/decline", controllers.DeclineInvite(db))
+
+	event.POST("/expenses", member, func(c *gin.Context) {
 		controllers.AddExpense(c, db)
 	})
-	r.GET("/events/:id/expenses", controllers.ListExpenses(db))
-	r.PUT("/expenses/:id", controllers.UpdateExpense(db))
-	r.DELETE("/expenses/:id", controllers.DeleteExpense(db))
+	event.GET("/expenses", controllers.ListExpenses(db))
+	event.POST("/receipts/fiscal", member, controllers.AddFiscalReceipt(db))
+	event.POST("/import", member, controllers.ImportExpenses(db))
+	event.POST("/recurring", member, controllers.CreateRecurringExpense(db))
+	event.GET("/recurring", controllers.ListRecurringExpenses(db))
+	event.POST("/recurring/:recurring_id/pause", member, controllers.RecurringAction(db, "pause"))
+	event.POST("/recurring/:recurring_id/resume", member, controllers.RecurringAction(db, "resume"))
+	event.POST("/recurring/:recurring_id/skip", member, controllers.RecurringAction(db, "skip"))
+	event.POST("/recurring/:recurring_id/end", member, controllers.RecurringAction(db, "end"))
+
+	expense := r.Group("/expenses/:id", middleware.ExpenseMember(db))
+	expense.PUT("", controllers.UpdateExpense(db))
+	expense.DELETE("", controllers.DeleteExpense(db, store))
+	expense.POST("/attachments", controllers.UploadAttachment(db, store))
+	expense.GET("/attachments", controllers.ListAttachments(db))
+	expense.GET("/attachments/:attachment_id", controllers.DownloadAttachment(db, store))
+	expense.DELETE("/attachments/:attachment_id", controllers.DeleteAttachment(db, store))
 
-	r.GET("/events/:id/summary", controllers.GetEventSummary(db))
+	event.GET("/summary", controllers.GetEventSummary(db))
+	event.GET("/export", controllers.ExportEvent(db))
 
-	r.GET("/events/:id/debts", controllers.GetDebts(db))
-	r.POST("/events/:id/payments", func(c *gin.Context) {
+	event.GET("/debts", controllers.GetDebts(db))
+	event.GET("/settle-plan", controllers.GetSettlePlan(db))
+	event.POST("/settle-plan", admin, controllers.ApplySettlePlan(db))
+	event.POST("/debts/recompute", admin, controllers.RecomputeDebts(db))
+	r.POST("/admin/events/:id/debts/recompute", middleware.SystemAdmin(db), controllers.RecomputeDebts(db))
+	event.GET("/debts/:debt_id/payment-request", controllers.GetPaymentRequest(db))
+	event.POST("/payments", member, func(c *gin.Context) {
 		controllers.AddPayment(c, db)
 	})
-	r.GET("/events/:id/payments", controllers.ListPayments(db))
+	event.GET("/payments", controllers.ListPayments(db))
+	event.POST("/payments/:payment_id/confirm", controllers.ConfirmPayment(db))
+	event.POST("/payments/:payment_id/dispute", controllers.DisputePayment(db))
+	event.POST("/payments/:payment_id/reverse", controllers.ReversePayment(db))
+	r.GET("/me/balances", controllers.GetMyBalances(db))
+	r.GET("/me/balances/:user_id", controllers.GetPairBalance(db))
+	r.POST("/me/balances/:user_id/settle", controllers.SettlePair(db))
+	r.GET("/me/payment-details", controllers.GetPaymentDetails(db))
+	r.PUT("/me/payment-details", controllers.UpdatePaymentDetails(db))
+	r.DELETE("/me/payment-details", controllers.DeletePaymentDetails(db))
 	r.POST("/name", controllers.UpdateUserName(db))
 }
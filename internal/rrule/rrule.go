@@ -0,0 +1,206 @@
+// Package rrule разбирает подмножество RFC 5545 RRULE, достаточное для
+// регулярных трат: FREQ=DAILY|WEEKLY|MONTHLY|YEARLY, INTERVAL, BYDAY (для
+// WEEKLY), BYMONTHDAY (для MONTHLY, -1 — последний день месяца), COUNT и UNTIL.
+//
+// Примеры: "FREQ=MONTHLY;BYMONTHDAY=5", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH",
+// "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=12".
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+var ErrInvalid = errors.New("некорректное расписание")
+
+// maxPeriods ограничивает перебор периодов, чтобы правило, не дающее
+// ни одной даты (например, BYMONTHDAY=31 раз в 12 месяцев с февраля), не зацикливалось.
+const maxPeriods = 100_000
+
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	Count      int
+	Until      *time.Time
+}
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+func Parse(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return r, fmt.Errorf("%w: пустое правило", ErrInvalid)
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return r, fmt.Errorf("%w: %q", ErrInvalid, part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToUpper(value))
+			switch r.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				return r, fmt.Errorf("%w: FREQ=%s не поддерживается", ErrInvalid, value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("%w: INTERVAL=%s", ErrInvalid, value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("%w: COUNT=%s", ErrInvalid, value)
+			}
+			r.Count = n
+		case "UNTIL":
+			t, err := parseUntil(value)
+			if err != nil {
+				return r, fmt.Errorf("%w: UNTIL=%s", ErrInvalid, value)
+			}
+			r.Until = &t
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := weekdays[strings.ToUpper(d)]
+				if !ok {
+					return r, fmt.Errorf("%w: BYDAY=%s", ErrInvalid, d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return r, fmt.Errorf("%w: BYMONTHDAY=%s", ErrInvalid, d)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		default:
+			return r, fmt.Errorf("%w: %s не поддерживается", ErrInvalid, key)
+		}
+	}
+
+	if r.Freq == "" {
+		return r, fmt.Errorf("%w: не указан FREQ", ErrInvalid)
+	}
+	if len(r.ByDay) > 0 && r.Freq != Weekly {
+		return r, fmt.Errorf("%w: BYDAY поддерживается только с FREQ=WEEKLY", ErrInvalid)
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq != Monthly {
+		return r, fmt.Errorf("%w: BYMONTHDAY поддерживается только с FREQ=MONTHLY", ErrInvalid)
+	}
+	return r, nil
+}
+
+func parseUntil(v string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102T150405", "20060102"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, ErrInvalid
+}
+
+// Next возвращает первое повторение строго после after для расписания,
+// начинающегося в start. ok = false, если повторений больше нет.
+func (r Rule) Next(start, after time.Time) (time.Time, bool) {
+	n := 0
+	for period := 0; period < maxPeriods; period++ {
+		for _, t := range r.period(start, period) {
+			if t.Before(start) {
+				continue
+			}
+			if r.Until != nil && t.After(*r.Until) {
+				return time.Time{}, false
+			}
+			n++
+			if r.Count > 0 && n > r.Count {
+				return time.Time{}, false
+			}
+			if t.After(after) {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// period возвращает отсортированные даты повторений в k-м периоде правила.
+func (r Rule) period(start time.Time, k int) []time.Time {
+	step := k * r.Interval
+	y, m, d := start.Date()
+	hh, mm, ss := start.Clock()
+	loc := start.Location()
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, hh, mm, ss, 0, loc)
+	}
+
+	var out []time.Time
+	switch r.Freq {
+	case Daily:
+		out = append(out, at(y, m, d+step))
+	case Weekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		// Неделя начинается с понедельника.
+		monday := d - (int(start.Weekday())+6)%7 + 7*step
+		for _, wd := range days {
+			out = append(out, at(y, m, monday+(int(wd)+6)%7))
+		}
+	case Monthly:
+		first := time.Date(y, m+time.Month(step), 1, 0, 0, 0, 0, loc)
+		last := first.AddDate(0, 1, -1).Day()
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{d}
+		}
+		for _, md := range days {
+			if md < 0 {
+				md = last + md + 1
+			}
+			if md < 1 || md > last {
+				continue
+			}
+			out = append(out, at(first.Year(), first.Month(), md))
+		}
+	case Yearly:
+		t := at(y+step, m, d)
+		if t.Day() == d {
+			out = append(out, t)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return out
+}
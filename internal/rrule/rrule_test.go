@@ -0,0 +1,245 @@
+package rrule
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// occurrences возвращает первые n повторений правила, начиная со start.
+func occurrences(t *testing.T, rule string, start time.Time, n int) []time.Time {
+	t.Helper()
+	r, err := Parse(rule)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", rule, err)
+	}
+	var out []time.Time
+	after := start.Add(-time.Nanosecond)
+	for len(out) < n {
+		next, ok := r.Next(start, after)
+		if !ok {
+			break
+		}
+		out = append(out, next)
+		after = next
+	}
+	return out
+}
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 10, 0, 0, 0, time.UTC)
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start time.Time
+		n     int
+		want  []time.Time
+	}{
+		{
+			name:  "daily",
+			rule:  "FREQ=DAILY",
+			start: date(2026, 2, 27),
+			n:     3,
+			want:  []time.Time{date(2026, 2, 27), date(2026, 2, 28), date(2026, 3, 1)},
+		},
+		{
+			name:  "daily interval",
+			rule:  "FREQ=DAILY;INTERVAL=10",
+			start: date(2026, 1, 25),
+			n:     3,
+			want:  []time.Time{date(2026, 1, 25), date(2026, 2, 4), date(2026, 2, 14)},
+		},
+		{
+			name:  "weekly on start weekday",
+			rule:  "FREQ=WEEKLY",
+			start: date(2026, 10, 15), // четверг
+			n:     3,
+			want:  []time.Time{date(2026, 10, 15), date(2026, 10, 22), date(2026, 10, 29)},
+		},
+		{
+			name:  "biweekly by day skips days before start",
+			rule:  "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH",
+			start: date(2026, 10, 14), // среда: понедельник этой недели уже прошёл
+			n:     4,
+			want:  []time.Time{date(2026, 10, 15), date(2026, 10, 26), date(2026, 10, 29), date(2026, 11, 9)},
+		},
+		{
+			name:  "weekly across year boundary",
+			rule:  "FREQ=WEEKLY;BYDAY=SU",
+			start: date(2026, 12, 27),
+			n:     2,
+			want:  []time.Time{date(2026, 12, 27), date(2027, 1, 3)},
+		},
+		{
+			name:  "monthly on day 5",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=5",
+			start: date(2026, 11, 1),
+			n:     3,
+			want:  []time.Time{date(2026, 11, 5), date(2026, 12, 5), date(2027, 1, 5)},
+		},
+		{
+			name:  "monthly on the 31st skips short months",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=31",
+			start: date(2027, 1, 1),
+			n:     3,
+			want:  []time.Time{date(2027, 1, 31), date(2027, 3, 31), date(2027, 5, 31)},
+		},
+		{
+			name:  "monthly without bymonthday uses start day",
+			rule:  "FREQ=MONTHLY",
+			start: date(2027, 1, 30),
+			n:     3,
+			want:  []time.Time{date(2027, 1, 30), date(2027, 3, 30), date(2027, 4, 30)},
+		},
+		{
+			name:  "monthly on the last day",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: date(2027, 12, 15),
+			n:     4,
+			want:  []time.Time{date(2027, 12, 31), date(2028, 1, 31), date(2028, 2, 29), date(2028, 3, 31)},
+		},
+		{
+			name:  "monthly on two days",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=15,1",
+			start: date(2026, 1, 10),
+			n:     3,
+			want:  []time.Time{date(2026, 1, 15), date(2026, 2, 1), date(2026, 2, 15)},
+		},
+		{
+			name:  "yearly on Feb 29 only in leap years",
+			rule:  "FREQ=YEARLY",
+			start: date(2024, 2, 29),
+			n:     3,
+			want:  []time.Time{date(2024, 2, 29), date(2028, 2, 29), date(2032, 2, 29)},
+		},
+		{
+			name:  "count",
+			rule:  "FREQ=MONTHLY;BYMONTHDAY=1;COUNT=3",
+			start: date(2026, 1, 1),
+			n:     10,
+			want:  []time.Time{date(2026, 1, 1), date(2026, 2, 1), date(2026, 3, 1)},
+		},
+		{
+			name:  "count counts from start, not from skipped dates",
+			rule:  "FREQ=WEEKLY;BYDAY=MO,FR;COUNT=3",
+			start: date(2026, 10, 14), // среда
+			n:     10,
+			want:  []time.Time{date(2026, 10, 16), date(2026, 10, 19), date(2026, 10, 23)},
+		},
+		{
+			name:  "until is inclusive",
+			rule:  "FREQ=DAILY;UNTIL=20260103T100000Z",
+			start: date(2026, 1, 1),
+			n:     10,
+			want:  []time.Time{date(2026, 1, 1), date(2026, 1, 2), date(2026, 1, 3)},
+		},
+		{
+			name:  "until as date",
+			rule:  "FREQ=WEEKLY;UNTIL=20260115",
+			start: date(2026, 1, 1),
+			n:     10,
+			want:  []time.Time{date(2026, 1, 1), date(2026, 1, 8)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := occurrences(t, tt.rule, tt.start, tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("occurrence %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// Повторения сохраняют местное время начала при переходе на летнее и зимнее время.
+func TestNextKeepsWallClockAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 28, 9, 0, 0, 0, berlin)
+	for _, tt := range []struct {
+		rule string
+		want []time.Time
+	}{
+		{"FREQ=DAILY", []time.Time{
+			time.Date(2026, 3, 28, 9, 0, 0, 0, berlin),
+			time.Date(2026, 3, 29, 9, 0, 0, 0, berlin),
+			time.Date(2026, 3, 30, 9, 0, 0, 0, berlin),
+		}},
+		{"FREQ=MONTHLY;BYMONTHDAY=25", []time.Time{
+			time.Date(2026, 4, 25, 9, 0, 0, 0, berlin),
+			time.Date(2026, 5, 25, 9, 0, 0, 0, berlin),
+			time.Date(2026, 6, 25, 9, 0, 0, 0, berlin),
+		}},
+	} {
+		got := occurrences(t, tt.rule, start, len(tt.want))
+		for i := range tt.want {
+			if i >= len(got) || !got[i].Equal(tt.want[i]) {
+				t.Fatalf("%s: got %v, want %v", tt.rule, got, tt.want)
+			}
+			if h, _, _ := got[i].Clock(); h != 9 {
+				t.Errorf("%s: occurrence %d at %v, want 09:00 local", tt.rule, i, got[i])
+			}
+		}
+	}
+	// 29 марта сутки короче: между повторениями 23 часа.
+	got := occurrences(t, "FREQ=DAILY", start, 2)
+	if d := got[1].Sub(got[0]); d != 23*time.Hour {
+		t.Errorf("gap across DST = %v, want 23h", d)
+	}
+}
+
+func TestNextNeverMatching(t *testing.T) {
+	// 30 февраля не бывает: правило не должно зацикливаться.
+	r, err := Parse("FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := date(2026, 2, 1)
+	if next, ok := r.Next(start, start); ok {
+		t.Fatalf("Next = %v, want none", next)
+	}
+}
+
+func TestParse(t *testing.T) {
+	r, err := Parse("RRULE:freq=weekly;interval=2;byday=mo,th;count=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Freq != Weekly || r.Interval != 2 || r.Count != 5 ||
+		len(r.ByDay) != 2 || r.ByDay[0] != time.Monday || r.ByDay[1] != time.Thursday {
+		t.Errorf("Parse = %+v", r)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"FREQ=HOURLY",
+		"INTERVAL=2",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;UNTIL=tomorrow",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYMONTHDAY=0",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYMONTHDAY=1",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ=DAILY;COUNT",
+	} {
+		if _, err := Parse(s); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Parse(%q) error = %v, want ErrInvalid", s, err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// Package scheduler периодически превращает наступившие повторения регулярных
+// трат в обычные траты.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"log/slog"
+	"split-the-bill/internal/models"
+	"split-the-bill/internal/recurring"
+	"split-the-bill/internal/split"
+	"time"
+)
+
+type Scheduler struct {
+	db       *gorm.DB
+	log      *slog.Logger
+	interval time.Duration
+	create   recurring.CreateFunc
+}
+
+// New создаёт планировщик; create создаёт трату для каждого повторения.
+func New(db *gorm.DB, log *slog.Logger, interval time.Duration, create recurring.CreateFunc) *Scheduler {
+	return &Scheduler{db: db, log: log, interval: interval, create: create}
+}
+
+// Run обрабатывает шаблоны сразу и затем каждые interval, пока не отменён ctx.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(now time.Time) {
+	var ids []uint
+	if err := s.db.Model(&models.RecurringExpense{}).
+		Where("status = ? AND next_run_at <= ?", models.RecurringActive, now).
+		Pluck("id", &ids).Error; err != nil {
+		s.log.Error("failed to load due recurring expenses", "error", err)
+		return
+	}
+
+	for _, id := range ids {
+		if err := s.process(id, now); err != nil {
+			s.log.Error("failed to materialize recurring expense", "id", id, "error", err)
+		}
+	}
+}
+
+// process создаёт траты одного шаблона в отдельной транзакции. Строка шаблона
+// блокируется, а NextRunAt сдвигается в той же транзакции, что и создание трат,
+// поэтому повторный запуск или второй экземпляр сервиса не создадут дублей.
+func (s *Scheduler) process(id uint, now time.Time) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var rec models.RecurringExpense
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id = ? AND status = ? AND next_run_at <= ?", id, models.RecurringActive, now).
+			First(&rec).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := tx.Where("recurring_expense_id = ?", rec.ID).Find(&rec.Shares).Error; err != nil {
+			return err
+		}
+
+		created, err := recurring.Materialize(tx, &rec, now, s.create)
+		if err != nil {
+			return err
+		}
+		if created > 0 {
+			s.log.Info("materialized recurring expense", "id", rec.ID, "created", created)
+		}
+		return nil
+	})
+
+	if errors.Is(err, split.ErrInvalid) {
+		// Деление больше не применимо (например, участник покинул событие) —
+		// ставим шаблон на паузу, чтобы не повторять ошибку на каждом проходе.
+		s.log.Warn("pausing recurring expense", "id", id, "error", err)
+		return s.db.Model(&models.RecurringExpense{}).Where("id = ?", id).
+			Update("status", models.RecurringPaused).Error
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package split
+
+import (
+	"fmt"
+	"split-the-bill/internal/money"
+)
+
+// AdjustmentKind — вид надбавки или скидки к чеку.
+type AdjustmentKind string
+
+const (
+	KindTax      AdjustmentKind = "tax"
+	KindTip      AdjustmentKind = "tip"
+	KindService  AdjustmentKind = "service"
+	KindDiscount AdjustmentKind = "discount"
+)
+
+// Distribution — как надбавка делится между участниками.
+type Distribution string
+
+const (
+	// DistributeProportional — пропорционально доле участника в подытоге.
+	DistributeProportional Distribution = "proportional"
+	// DistributeEqual — поровну между всеми, у кого есть доля в подытоге.
+	DistributeEqual Distribution = "equal"
+)
+
+// Adjustment задаётся либо процентом от подытога, либо фиксированной суммой.
+// Скидка всегда уменьшает сумму, остальные виды — увеличивают.
+type Adjustment struct {
+	Kind         AdjustmentKind
+	Percent      money.Percent
+	Amount       money.Money
+	Distribution Distribution
+}
+
+// Breakdown — доля участника с разбивкой по видам надбавок.
+type Breakdown struct {
+	UserID      uint
+	Subtotal    money.Money
+	Adjustments map[AdjustmentKind]money.Money
+	Total       money.Money
+}
+
+func (a Adjustment) Validate() error {
+	switch a.Kind {
+	case KindTax, KindTip, KindService, KindDiscount:
+	default:
+		return fmt.Errorf("%w: неизвестный вид надбавки %q", ErrInvalid, a.Kind)
+	}
+	switch a.Distribution {
+	case "", DistributeProportional, DistributeEqual:
+	default:
+		return fmt.Errorf("%w: неизвестный способ распределения %q", ErrInvalid, a.Distribution)
+	}
+	if a.Percent < 0 || a.Amount < 0 {
+		return fmt.Errorf("%w: надбавка задаётся неотрицательным числом", ErrInvalid)
+	}
+	if (a.Percent != 0) == (a.Amount != 0) {
+		return fmt.Errorf("%w: у надбавки %s должен быть задан либо процент, либо сумма", ErrInvalid, a.Kind)
+	}
+	return nil
+}
+
+// Resolve возвращает сумму надбавки для подытога subtotal; у скидки она отрицательна.
+func (a Adjustment) Resolve(subtotal money.Money) money.Money {
+	amount := a.Amount
+	if a.Percent != 0 {
+		amount = a.Percent.Of(subtotal)
+	}
+	if a.Kind == KindDiscount {
+		return -amount
+	}
+	return amount
+}
+
+// Adjust раскладывает надбавки по участникам с долями base и возвращает
+// разбивку по каждому участнику. Сумма Total по всем участникам равна
+// сумме base плюс сумма Resolve всех надбавок.
+func Adjust(base []Share, adjustments []Adjustment, rem Remainder) ([]Breakdown, error) {
+	var subtotal money.Money
+	users := make([]uint, len(base))
+	proportional := make([]int64, len(base))
+	equal := make([]int64, len(base))
+	out := make([]Breakdown, len(base))
+	for i, s := range base {
+		subtotal += s.Amount
+		users[i] = s.UserID
+		proportional[i] = int64(s.Amount)
+		if s.Amount > 0 {
+			equal[i] = 1
+		}
+		out[i] = Breakdown{
+			UserID:      s.UserID,
+			Subtotal:    s.Amount,
+			Adjustments: make(map[AdjustmentKind]money.Money),
+			Total:       s.Amount,
+		}
+	}
+
+	for _, a := range adjustments {
+		if err := a.Validate(); err != nil {
+			return nil, err
+		}
+
+		weights := proportional
+		if a.Distribution == DistributeEqual {
+			weights = equal
+		}
+		amounts, err := Allocate(a.Resolve(subtotal), users, weights, rem)
+		if err != nil {
+			return nil, err
+		}
+		for i, amount := range amounts {
+			out[i].Adjustments[a.Kind] += amount
+			out[i].Total += amount
+		}
+	}
+
+	for _, b := range out {
+		if b.Total < 0 {
+			return nil, fmt.Errorf("%w: скидка больше доли пользователя %d", ErrInvalid, b.UserID)
+		}
+	}
+	return out, nil
+}
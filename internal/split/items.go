@@ -0,0 +1,70 @@
+package split
+
+import (
+	"fmt"
+	"sort"
+	"split-the-bill/internal/money"
+)
+
+// Item — позиция чека. Её стоимость делится между Users пропорционально
+// Portions (по умолчанию поровну); позиция без Users делится на всех Participants.
+type Item struct {
+	Total    money.Money
+	Users    []uint
+	Portions []int64
+}
+
+// Items считает доли по позициям чека: каждая позиция делится отдельно
+// с распределением остатка по rem, затем доли участника складываются.
+func Items(items []Item, participants []uint, rem Remainder) ([]Share, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("%w: в чеке нет позиций", ErrInvalid)
+	}
+
+	allowed := make(map[uint]bool, len(participants))
+	for _, id := range participants {
+		allowed[id] = true
+	}
+
+	totals := make(map[uint]money.Money)
+	for n, item := range items {
+		if item.Total < 0 {
+			return nil, fmt.Errorf("%w: позиция %d с отрицательной стоимостью", ErrInvalid, n+1)
+		}
+
+		users, portions := item.Users, item.Portions
+		if len(users) == 0 {
+			users = sortedUsers(participants)
+			portions = nil
+		}
+		if portions == nil {
+			portions = ones(len(users))
+		}
+
+		seen := make(map[uint]bool, len(users))
+		for _, id := range users {
+			if seen[id] {
+				return nil, fmt.Errorf("%w: пользователь %d указан в позиции %d дважды", ErrInvalid, id, n+1)
+			}
+			if !allowed[id] {
+				return nil, fmt.Errorf("%w: пользователь %d не участвует в событии", ErrInvalid, id)
+			}
+			seen[id] = true
+		}
+
+		amounts, err := Allocate(item.Total, users, portions, rem)
+		if err != nil {
+			return nil, fmt.Errorf("позиция %d: %w", n+1, err)
+		}
+		for i, id := range users {
+			totals[id] += amounts[i]
+		}
+	}
+
+	shares := make([]Share, 0, len(totals))
+	for id, amount := range totals {
+		shares = append(shares, Share{UserID: id, Amount: amount})
+	}
+	sort.Slice(shares, func(i, j int) bool { return shares[i].UserID < shares[j].UserID })
+	return shares, nil
+}
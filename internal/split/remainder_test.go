@@ -0,0 +1,185 @@
+package split
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"split-the-bill/internal/money"
+	"testing"
+)
+
+func TestAllocatePolicies(t *testing.T) {
+	users := []uint{10, 20, 30, 40}
+	equal := []int64{1, 1, 1, 1}
+	tests := []struct {
+		name    string
+		total   money.Money
+		weights []int64
+		rem     Remainder
+		want    []money.Money
+	}{
+		{
+			name:    "payer takes the whole remainder",
+			total:   1003,
+			weights: equal,
+			rem:     Remainder{Policy: PolicyPayer, Payer: 30},
+			want:    []money.Money{250, 250, 253, 250},
+		},
+		{
+			name:    "payer outside the split falls back to round robin",
+			total:   1003,
+			weights: equal,
+			rem:     Remainder{Policy: PolicyPayer, Payer: 99},
+			want:    []money.Money{251, 251, 251, 250},
+		},
+		{
+			name:    "round robin from the start",
+			total:   1002,
+			weights: equal,
+			rem:     Remainder{Policy: PolicyRoundRobin},
+			want:    []money.Money{251, 251, 250, 250},
+		},
+		{
+			name:    "round robin offset by seed",
+			total:   1002,
+			weights: equal,
+			rem:     Remainder{Policy: PolicyRoundRobin, Seed: 3},
+			want:    []money.Money{251, 250, 250, 251},
+		},
+		{
+			name:    "round robin seed wraps",
+			total:   1001,
+			weights: equal,
+			rem:     Remainder{Policy: PolicyRoundRobin, Seed: 6},
+			want:    []money.Money{250, 250, 251, 250},
+		},
+		{
+			name:    "largest shares first",
+			total:   1002,
+			weights: []int64{1, 3, 1, 2},
+			rem:     Remainder{Policy: PolicyLargest},
+			want:    []money.Money{143, 430, 143, 286},
+		},
+		{
+			name:    "largest ties keep user order",
+			total:   1001,
+			weights: equal,
+			rem:     Remainder{Policy: PolicyLargest},
+			want:    []money.Money{251, 250, 250, 250},
+		},
+		{
+			name:    "negative total mirrors positive",
+			total:   -1003,
+			weights: equal,
+			rem:     Remainder{Policy: PolicyPayer, Payer: 30},
+			want:    []money.Money{-250, -250, -253, -250},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Allocate(tt.total, users, tt.weights, tt.rem)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Allocate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Каждое правило детерминировано: одинаковые входные данные дают одинаковый
+// результат, а сумма всегда равна total.
+func TestAllocateDeterministic(t *testing.T) {
+	users := []uint{1, 2, 3, 4, 5, 6, 7}
+	weights := []int64{3, 1, 4, 1, 5, 9, 2}
+	for _, policy := range []Policy{PolicyPayer, PolicyRoundRobin, PolicyLargest, PolicyRandom} {
+		for seed := int64(-3); seed < 20; seed++ {
+			rem := Remainder{Policy: policy, Payer: 4, Seed: seed}
+			for total := money.Money(-500); total <= 5000; total += 97 {
+				first, err := Allocate(total, users, weights, rem)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < 3; i++ {
+					again, _ := Allocate(total, users, weights, rem)
+					if !reflect.DeepEqual(first, again) {
+						t.Fatalf("%s seed=%d total=%s: %v then %v", policy, seed, total, first, again)
+					}
+				}
+
+				var sum money.Money
+				for i, a := range first {
+					sum += a
+					// Никто не получает больше, чем на копейку сверх точной доли, кроме плательщика.
+					exact := int64(total) * weights[i] / 25
+					if policy != PolicyPayer && (int64(a)-exact > 1 || exact-int64(a) > 1) {
+						t.Fatalf("%s seed=%d total=%s: user %d got %s, exact share %d", policy, seed, total, users[i], a, exact)
+					}
+				}
+				if sum != total {
+					t.Fatalf("%s seed=%d total=%s: sum %s", policy, seed, total, sum)
+				}
+			}
+		}
+	}
+}
+
+// Случайное правило зависит только от Seed: разные Seed дают разные раскладки,
+// но одна и та же трата всегда раскладывается одинаково.
+func TestAllocateRandomUsesSeed(t *testing.T) {
+	users := []uint{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	weights := make([]int64, len(users))
+	for i := range weights {
+		weights[i] = 1
+	}
+	seen := make(map[string]bool)
+	for seed := int64(0); seed < 20; seed++ {
+		got, err := Allocate(1005, users, weights, Remainder{Policy: PolicyRandom, Seed: seed})
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[fmtAmounts(got)] = true
+	}
+	if len(seen) < 2 {
+		t.Error("random policy ignores seed")
+	}
+}
+
+func fmtAmounts(amounts []money.Money) string {
+	s := ""
+	for _, a := range amounts {
+		s += a.String() + ","
+	}
+	return s
+}
+
+func TestAllocateInvalid(t *testing.T) {
+	users := []uint{1, 2}
+	tests := []struct {
+		name    string
+		total   money.Money
+		users   []uint
+		weights []int64
+		rem     Remainder
+	}{
+		{"weights mismatch", 100, users, []int64{1}, Remainder{}},
+		{"negative weight", 100, users, []int64{2, -1}, Remainder{}},
+		{"all zero weights", 100, users, []int64{0, 0}, Remainder{}},
+		{"unknown policy", 100, users, []int64{1, 1}, Remainder{Policy: "coin"}},
+		{"weights overflow", 100, []uint{1, 2, 3}, []int64{math.MaxInt64, math.MaxInt64, math.MaxInt64}, Remainder{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Allocate(tt.total, tt.users, tt.weights, tt.rem); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Allocate = %v, %v; want ErrInvalid", got, err)
+			}
+		})
+	}
+
+	// Нулевую сумму можно «разделить» и при нулевых весах.
+	got, err := Allocate(0, users, []int64{0, 0}, Remainder{})
+	if err != nil || !reflect.DeepEqual(got, []money.Money{0, 0}) {
+		t.Errorf("Allocate(0) = %v, %v", got, err)
+	}
+}
@@ -0,0 +1,320 @@
+// Package split считает доли участников в трате по выбранному способу деления.
+package split
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"math/rand"
+	"sort"
+	"split-the-bill/internal/money"
+)
+
+type Mode string
+
+const (
+	// ModeEqual — поровну между выбранными участниками (или всеми участниками события).
+	ModeEqual Mode = "equal"
+	// ModeExact — доли заданы точными суммами.
+	ModeExact Mode = "exact"
+	// ModePercentage — доли заданы процентами, в сумме 100%.
+	ModePercentage Mode = "percentage"
+	// ModeWeights — доли пропорциональны весам.
+	ModeWeights Mode = "weights"
+	// ModeExcept — поровну между всеми участниками события, кроме перечисленных.
+	ModeExcept Mode = "except"
+	// ModeItemized — доли складываются из позиций чека, см. Items.
+	ModeItemized Mode = "itemized"
+)
+
+// Policy определяет, кому достаются копейки, оставшиеся после деления нацело.
+type Policy string
+
+const (
+	// PolicyPayer — весь остаток достаётся плательщику.
+	PolicyPayer Policy = "payer"
+	// PolicyRoundRobin — по копейке по кругу, начиная со смещения Seed.
+	PolicyRoundRobin Policy = "round_robin"
+	// PolicyLargest — по копейке, начиная с самых крупных долей.
+	PolicyLargest Policy = "largest"
+	// PolicyRandom — по копейке в случайном порядке, детерминированном Seed.
+	PolicyRandom Policy = "random"
+)
+
+const DefaultPolicy = PolicyPayer
+
+var ErrInvalid = errors.New("некорректное деление траты")
+
+func (m Mode) Valid() bool {
+	switch m {
+	case ModeEqual, ModeExact, ModePercentage, ModeWeights, ModeExcept, ModeItemized:
+		return true
+	}
+	return false
+}
+
+func (p Policy) Valid() bool {
+	switch p {
+	case PolicyPayer, PolicyRoundRobin, PolicyLargest, PolicyRandom:
+		return true
+	}
+	return false
+}
+
+// Remainder — правило распределения остатка для конкретной траты.
+type Remainder struct {
+	Policy Policy
+	Payer  uint
+	Seed   int64
+}
+
+// Part — параметр деления для одного участника; какое поле используется, зависит от Mode.
+type Part struct {
+	UserID  uint
+	Amount  money.Money
+	Percent money.Percent
+	Weight  int64
+}
+
+type Share struct {
+	UserID uint
+	Amount money.Money
+}
+
+type Request struct {
+	Mode  Mode
+	Total money.Money
+	// Participants — все участники события; нужны для equal без списка и для except.
+	Participants []uint
+	Parts        []Part
+	Remainder    Remainder
+}
+
+// Compute возвращает доли, отсортированные по UserID; их сумма всегда равна Total.
+func Compute(r Request) ([]Share, error) {
+	if r.Total < 0 {
+		return nil, fmt.Errorf("%w: сумма не может быть отрицательной", ErrInvalid)
+	}
+	if !r.Mode.Valid() {
+		return nil, fmt.Errorf("%w: неизвестный способ деления %q", ErrInvalid, r.Mode)
+	}
+	if r.Remainder.Policy == "" {
+		r.Remainder.Policy = DefaultPolicy
+	}
+	if !r.Remainder.Policy.Valid() {
+		return nil, fmt.Errorf("%w: неизвестное правило остатка %q", ErrInvalid, r.Remainder.Policy)
+	}
+
+	parts := append([]Part(nil), r.Parts...)
+	sort.Slice(parts, func(i, j int) bool { return parts[i].UserID < parts[j].UserID })
+	if err := checkParts(parts, r.Participants); err != nil {
+		return nil, err
+	}
+
+	switch r.Mode {
+	case ModeExact:
+		return exact(r.Total, parts)
+	case ModeEqual:
+		users := userIDs(parts)
+		if len(users) == 0 {
+			users = sortedUsers(r.Participants)
+		}
+		return weighted(r.Total, users, ones(len(users)), r.Remainder)
+	case ModeExcept:
+		excluded := make(map[uint]bool, len(parts))
+		for _, p := range parts {
+			excluded[p.UserID] = true
+		}
+		var users []uint
+		for _, id := range sortedUsers(r.Participants) {
+			if !excluded[id] {
+				users = append(users, id)
+			}
+		}
+		return weighted(r.Total, users, ones(len(users)), r.Remainder)
+	case ModePercentage:
+		var sum money.Percent
+		weights := make([]int64, len(parts))
+		for i, p := range parts {
+			if p.Percent < 0 {
+				return nil, fmt.Errorf("%w: отрицательный процент", ErrInvalid)
+			}
+			sum += p.Percent
+			weights[i] = int64(p.Percent)
+		}
+		if sum != money.Hundred {
+			return nil, fmt.Errorf("%w: проценты в сумме дают %s%%, а не 100%%", ErrInvalid, sum)
+		}
+		return weighted(r.Total, userIDs(parts), weights, r.Remainder)
+	case ModeWeights:
+		weights := make([]int64, len(parts))
+		for i, p := range parts {
+			if p.Weight < 0 {
+				return nil, fmt.Errorf("%w: отрицательный вес", ErrInvalid)
+			}
+			weights[i] = p.Weight
+		}
+		return weighted(r.Total, userIDs(parts), weights, r.Remainder)
+	case ModeItemized:
+		return nil, fmt.Errorf("%w: доли по позициям считает Items", ErrInvalid)
+	}
+	return nil, fmt.Errorf("%w: неизвестный способ деления %q", ErrInvalid, r.Mode)
+}
+
+// Allocate делит total между users пропорционально весам. Каждый получает
+// округлённую вниз долю, а оставшиеся копейки распределяются по правилу rem,
+// так что сумма результата всегда точно равна total.
+func Allocate(total money.Money, users []uint, weights []int64, rem Remainder) ([]money.Money, error) {
+	if len(users) != len(weights) {
+		return nil, fmt.Errorf("%w: число весов не совпадает с числом участников", ErrInvalid)
+	}
+	if rem.Policy == "" {
+		rem.Policy = DefaultPolicy
+	}
+	if !rem.Policy.Valid() {
+		return nil, fmt.Errorf("%w: неизвестное правило остатка %q", ErrInvalid, rem.Policy)
+	}
+
+	neg := total < 0
+	if neg {
+		total = -total
+	}
+
+	var sum uint64
+	for _, w := range weights {
+		if w < 0 {
+			return nil, fmt.Errorf("%w: отрицательный вес", ErrInvalid)
+		}
+		var carry uint64
+		sum, carry = bits.Add64(sum, uint64(w), 0)
+		if carry != 0 {
+			return nil, fmt.Errorf("%w: слишком большие веса", ErrInvalid)
+		}
+	}
+	if sum == 0 {
+		if total == 0 {
+			return make([]money.Money, len(weights)), nil
+		}
+		return nil, fmt.Errorf("%w: не на кого делить сумму", ErrInvalid)
+	}
+
+	amounts := make([]money.Money, len(weights))
+	rest := total
+	var eligible []int
+	for i, w := range weights {
+		hi, lo := bits.Mul64(uint64(total), uint64(w))
+		q, _ := bits.Div64(hi, lo, sum)
+		amounts[i] = money.Money(q)
+		rest -= amounts[i]
+		if w > 0 {
+			eligible = append(eligible, i)
+		}
+	}
+
+	order := remainderOrder(eligible, users, amounts, rem)
+	for i := 0; rest > 0; i = (i + 1) % len(order) {
+		amounts[order[i]]++
+		rest--
+	}
+
+	if neg {
+		for i := range amounts {
+			amounts[i] = -amounts[i]
+		}
+	}
+	return amounts, nil
+}
+
+// remainderOrder возвращает порядок, в котором индексы получают по копейке остатка.
+func remainderOrder(eligible []int, users []uint, amounts []money.Money, rem Remainder) []int {
+	order := append([]int(nil), eligible...)
+	switch rem.Policy {
+	case PolicyPayer:
+		for _, i := range eligible {
+			if users[i] == rem.Payer {
+				return []int{i}
+			}
+		}
+		// Плательщик не участвует в делении — раздаём по кругу.
+	case PolicyRoundRobin:
+		offset := int(uint64(rem.Seed) % uint64(len(order)))
+		order = append(order[offset:], order[:offset]...)
+	case PolicyLargest:
+		sort.SliceStable(order, func(a, b int) bool {
+			return amounts[order[a]] > amounts[order[b]]
+		})
+	case PolicyRandom:
+		rnd := rand.New(rand.NewSource(rem.Seed))
+		rnd.Shuffle(len(order), func(a, b int) { order[a], order[b] = order[b], order[a] })
+	}
+	return order
+}
+
+func exact(total money.Money, parts []Part) ([]Share, error) {
+	var sum money.Money
+	shares := make([]Share, len(parts))
+	for i, p := range parts {
+		if p.Amount < 0 {
+			return nil, fmt.Errorf("%w: отрицательная доля", ErrInvalid)
+		}
+		sum += p.Amount
+		shares[i] = Share{UserID: p.UserID, Amount: p.Amount}
+	}
+	if sum != total {
+		return nil, fmt.Errorf("%w: сумма долей %s не совпадает с общей суммой %s", ErrInvalid, sum, total)
+	}
+	return shares, nil
+}
+
+func weighted(total money.Money, users []uint, weights []int64, rem Remainder) ([]Share, error) {
+	if len(users) == 0 {
+		return nil, fmt.Errorf("%w: не на кого делить сумму", ErrInvalid)
+	}
+	amounts, err := Allocate(total, users, weights, rem)
+	if err != nil {
+		return nil, err
+	}
+	shares := make([]Share, len(users))
+	for i, id := range users {
+		shares[i] = Share{UserID: id, Amount: amounts[i]}
+	}
+	return shares, nil
+}
+
+func checkParts(parts []Part, participants []uint) error {
+	allowed := make(map[uint]bool, len(participants))
+	for _, id := range participants {
+		allowed[id] = true
+	}
+	for i, p := range parts {
+		if i > 0 && parts[i-1].UserID == p.UserID {
+			return fmt.Errorf("%w: пользователь %d указан дважды", ErrInvalid, p.UserID)
+		}
+		if participants != nil && !allowed[p.UserID] {
+			return fmt.Errorf("%w: пользователь %d не участвует в событии", ErrInvalid, p.UserID)
+		}
+	}
+	return nil
+}
+
+func userIDs(parts []Part) []uint {
+	ids := make([]uint, len(parts))
+	for i, p := range parts {
+		ids[i] = p.UserID
+	}
+	return ids
+}
+
+func sortedUsers(ids []uint) []uint {
+	ids = append([]uint(nil), ids...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func ones(n int) []int64 {
+	w := make([]int64, n)
+	for i := range w {
+		w[i] = 1
+	}
+	return w
+}
@@ -0,0 +1,166 @@
+package split
+
+import (
+	"errors"
+	"reflect"
+	"split-the-bill/internal/money"
+	"testing"
+)
+
+func sum(shares []Share) money.Money {
+	var s money.Money
+	for _, sh := range shares {
+		s += sh.Amount
+	}
+	return s
+}
+
+func TestCompute(t *testing.T) {
+	participants := []uint{3, 1, 2}
+	tests := []struct {
+		name string
+		req  Request
+		want []Share
+	}{
+		{
+			name: "equal between all participants",
+			req:  Request{Mode: ModeEqual, Total: 900, Participants: participants},
+			want: []Share{{1, 300}, {2, 300}, {3, 300}},
+		},
+		{
+			name: "equal remainder goes to payer",
+			req: Request{Mode: ModeEqual, Total: 1000, Participants: participants,
+				Remainder: Remainder{Policy: PolicyPayer, Payer: 2}},
+			want: []Share{{1, 333}, {2, 334}, {3, 333}},
+		},
+		{
+			name: "equal between listed users",
+			req: Request{Mode: ModeEqual, Total: 1001, Participants: participants,
+				Parts: []Part{{UserID: 3}, {UserID: 1}}, Remainder: Remainder{Payer: 3}},
+			want: []Share{{1, 500}, {3, 501}},
+		},
+		{
+			name: "exact",
+			req: Request{Mode: ModeExact, Total: 1000, Participants: participants,
+				Parts: []Part{{UserID: 2, Amount: 250}, {UserID: 1, Amount: 750}}},
+			want: []Share{{1, 750}, {2, 250}},
+		},
+		{
+			name: "percentage",
+			req: Request{Mode: ModePercentage, Total: 10000, Participants: participants,
+				Parts: []Part{{UserID: 1, Percent: 5000}, {UserID: 2, Percent: 3000}, {UserID: 3, Percent: 2000}}},
+			want: []Share{{1, 5000}, {2, 3000}, {3, 2000}},
+		},
+		{
+			name: "percentage thirds reconcile to the kopeck",
+			req: Request{Mode: ModePercentage, Total: 10000, Participants: participants,
+				Parts:     []Part{{UserID: 1, Percent: 3333}, {UserID: 2, Percent: 3333}, {UserID: 3, Percent: 3334}},
+				Remainder: Remainder{Payer: 1}},
+			want: []Share{{1, 3333}, {2, 3333}, {3, 3334}},
+		},
+		{
+			name: "weights",
+			req: Request{Mode: ModeWeights, Total: 1000, Participants: participants,
+				Parts: []Part{{UserID: 1, Weight: 2}, {UserID: 2, Weight: 1}, {UserID: 3, Weight: 1}}},
+			want: []Share{{1, 500}, {2, 250}, {3, 250}},
+		},
+		{
+			name: "zero weight gets nothing, even of the remainder",
+			req: Request{Mode: ModeWeights, Total: 1001, Participants: participants,
+				Parts:     []Part{{UserID: 1, Weight: 1}, {UserID: 2, Weight: 0}, {UserID: 3, Weight: 1}},
+				Remainder: Remainder{Policy: PolicyRoundRobin}},
+			want: []Share{{1, 501}, {2, 0}, {3, 500}},
+		},
+		{
+			name: "except",
+			req: Request{Mode: ModeExcept, Total: 1000, Participants: participants,
+				Parts: []Part{{UserID: 2}}, Remainder: Remainder{Payer: 3}},
+			want: []Share{{1, 500}, {3, 500}},
+		},
+		{
+			name: "zero total",
+			req:  Request{Mode: ModeEqual, Total: 0, Participants: participants},
+			want: []Share{{1, 0}, {2, 0}, {3, 0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Compute(tt.req)
+			if err != nil {
+				t.Fatalf("Compute: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compute = %v, want %v", got, tt.want)
+			}
+			if sum(got) != tt.req.Total {
+				t.Errorf("shares sum to %s, want %s", sum(got), tt.req.Total)
+			}
+		})
+	}
+}
+
+// Сумма долей сходится с общей суммой до копейки при любых суммах и делителях.
+func TestComputeReconciles(t *testing.T) {
+	participants := []uint{1, 2, 3, 4, 5, 6, 7}
+	for _, mode := range []Mode{ModeEqual, ModeWeights} {
+		for _, policy := range []Policy{PolicyPayer, PolicyRoundRobin, PolicyLargest, PolicyRandom} {
+			for total := money.Money(0); total < 2000; total += 37 {
+				for n := 1; n <= len(participants); n++ {
+					req := Request{Mode: mode, Total: total, Participants: participants,
+						Remainder: Remainder{Policy: policy, Payer: 1, Seed: int64(total)}}
+					for i := 0; i < n; i++ {
+						req.Parts = append(req.Parts, Part{UserID: participants[i], Weight: int64(i + 1)})
+					}
+					shares, err := Compute(req)
+					if err != nil {
+						t.Fatalf("%s/%s total=%s n=%d: %v", mode, policy, total, n, err)
+					}
+					if sum(shares) != total {
+						t.Fatalf("%s/%s total=%s n=%d: shares sum to %s", mode, policy, total, n, sum(shares))
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestComputeInvalid(t *testing.T) {
+	participants := []uint{1, 2, 3}
+	tests := []struct {
+		name string
+		req  Request
+	}{
+		{"negative total", Request{Mode: ModeEqual, Total: -1, Participants: participants}},
+		{"unknown mode", Request{Mode: "halves", Total: 100, Participants: participants}},
+		{"unknown policy", Request{Mode: ModeEqual, Total: 100, Participants: participants,
+			Remainder: Remainder{Policy: "coin"}}},
+		{"itemized needs items", Request{Mode: ModeItemized, Total: 100, Participants: participants}},
+		{"duplicate user", Request{Mode: ModeExact, Total: 100, Participants: participants,
+			Parts: []Part{{UserID: 1, Amount: 50}, {UserID: 1, Amount: 50}}}},
+		{"not a participant", Request{Mode: ModeExact, Total: 100, Participants: participants,
+			Parts: []Part{{UserID: 9, Amount: 100}}}},
+		{"exact does not add up", Request{Mode: ModeExact, Total: 100, Participants: participants,
+			Parts: []Part{{UserID: 1, Amount: 50}, {UserID: 2, Amount: 49}}}},
+		{"exact negative", Request{Mode: ModeExact, Total: 100, Participants: participants,
+			Parts: []Part{{UserID: 1, Amount: 150}, {UserID: 2, Amount: -50}}}},
+		{"percent under 100", Request{Mode: ModePercentage, Total: 100, Participants: participants,
+			Parts: []Part{{UserID: 1, Percent: 5000}, {UserID: 2, Percent: 4999}}}},
+		{"percent negative", Request{Mode: ModePercentage, Total: 100, Participants: participants,
+			Parts: []Part{{UserID: 1, Percent: 11000}, {UserID: 2, Percent: -1000}}}},
+		{"weights all zero", Request{Mode: ModeWeights, Total: 100, Participants: participants,
+			Parts: []Part{{UserID: 1}, {UserID: 2}}}},
+		{"weights negative", Request{Mode: ModeWeights, Total: 100, Participants: participants,
+			Parts: []Part{{UserID: 1, Weight: 2}, {UserID: 2, Weight: -1}}}},
+		{"weights empty", Request{Mode: ModeWeights, Total: 100, Participants: participants}},
+		{"except everyone", Request{Mode: ModeExcept, Total: 100, Participants: participants,
+			Parts: []Part{{UserID: 1}, {UserID: 2}, {UserID: 3}}}},
+		{"equal without participants", Request{Mode: ModeEqual, Total: 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := Compute(tt.req); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Compute = %v, %v; want ErrInvalid", got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ErrNotFound возвращается, если объекта с таким ключом нет.
+var ErrNotFound = errors.New("storage: object not found")
+
+// Storage хранит файлы вложений по ключу. Ключи выдаёт приложение,
+// например "expenses/12/K5QX...".
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FromEnv создаёт хранилище по переменным окружения:
+//
+//	STORAGE_DRIVER  local (по умолчанию) или s3
+//	STORAGE_DIR     каталог для local, по умолчанию ./data/attachments
+//	S3_ENDPOINT, S3_ACCESS_KEY, S3_SECRET_KEY, S3_BUCKET, S3_REGION, S3_USE_SSL
+func FromEnv() (Storage, error) {
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "./data/attachments"
+		}
+		return NewLocal(dir)
+	case "s3":
+		return NewS3(context.Background(), S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			UseSSL:    strings.EqualFold(os.Getenv("S3_USE_SSL"), "true"),
+		})
+	default:
+		return nil, fmt.Errorf("storage: unknown STORAGE_DRIVER %q", driver)
+	}
+}
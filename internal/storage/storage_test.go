@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testStorage проверяет общий контракт Storage.
+func testStorage(t *testing.T, s Storage) {
+	ctx := context.Background()
+	key := "expenses/12/receipt"
+	data := []byte("receipt contents")
+
+	if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get before Put: error = %v, want ErrNotFound", err)
+	}
+	if err := s.Put(ctx, key, bytes.NewReader(data), int64(len(data)), "image/png"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	assertObject(t, s, key, data)
+
+	replaced := []byte("replaced")
+	if err := s.Put(ctx, key, bytes.NewReader(replaced), int64(len(replaced)), "image/png"); err != nil {
+		t.Fatalf("Put again: %v", err)
+	}
+	assertObject(t, s, key, replaced)
+
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := s.Get(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete: error = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete missing object: %v", err)
+	}
+}
+
+func assertObject(t *testing.T, s Storage, key string, want []byte) {
+	t.Helper()
+	r, err := s.Get(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	defer r.Close()
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Get = %q, want %q", got, want)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, s)
+
+	// После атомарной записи не остаётся временных файлов.
+	if err := s.Put(context.Background(), "a/b", strings.NewReader("x"), 1, ""); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "b" {
+		t.Fatalf("unexpected files: %v", entries)
+	}
+}
+
+func TestLocalRejectsEscapingKeys(t *testing.T) {
+	s, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"../outside", "/etc/passwd", "a/../../b", ""} {
+		if err := s.Put(context.Background(), key, strings.NewReader("x"), 1, ""); err == nil {
+			t.Errorf("Put(%q) succeeded", key)
+		}
+		if _, err := s.Get(context.Background(), key); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("Get(%q) error = %v, want invalid key", key, err)
+		}
+	}
+}
+
+// s3Stub — минимальная замена S3/MinIO в памяти: бакеты и объекты по путям
+// /bucket и /bucket/key, как их адресует minio-go для локального endpoint.
+type s3Stub struct {
+	mu      sync.Mutex
+	buckets map[string]bool
+	objects map[string][]byte
+}
+
+func (s *s3Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if key == "" {
+		switch r.Method {
+		case http.MethodHead:
+			if !s.buckets[bucket] {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case http.MethodPut:
+			s.buckets[bucket] = true
+		case http.MethodGet:
+			if r.URL.Query().Has("location") {
+				io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`)
+				return
+			}
+			w.WriteHeader(http.StatusNotImplemented)
+		default:
+			w.WriteHeader(http.StatusNotImplemented)
+		}
+		return
+	}
+	if !s.buckets[bucket] {
+		writeS3Error(w, http.StatusNotFound, "NoSuchBucket")
+		return
+	}
+
+	name := bucket + "/" + key
+	switch r.Method {
+	case http.MethodPut:
+		body, err := readS3Body(r)
+		if err != nil {
+			writeS3Error(w, http.StatusBadRequest, "IncompleteBody")
+			return
+		}
+		s.objects[name] = body
+		w.Header().Set("ETag", `"stub"`)
+	case http.MethodHead, http.MethodGet:
+		data, ok := s.objects[name]
+		if !ok {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			writeS3Error(w, http.StatusNotFound, "NoSuchKey")
+			return
+		}
+		w.Header().Set("ETag", `"stub"`)
+		w.Header().Set("Last-Modified", time.Now().UTC().Format(http.TimeFormat))
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		delete(s.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// readS3Body читает тело PUT, разворачивая aws-chunked, которым minio-go
+// подписывает загрузки по HTTP.
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+	var body []byte
+	br := bufio.NewReader(r.Body)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		sizeHex, _, _ := strings.Cut(strings.TrimSpace(line), ";")
+		size, err := strconv.ParseInt(sizeHex, 16, 64)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return body, nil
+		}
+		chunk := make([]byte, size+2)
+		if _, err := io.ReadFull(br, chunk); err != nil {
+			return nil, err
+		}
+		body = append(body, chunk[:size]...)
+	}
+}
+
+func writeS3Error(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	io.WriteString(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>`+code+`</Code><Message>`+code+`</Message></Error>`)
+}
+
+func TestS3(t *testing.T) {
+	stub := &s3Stub{buckets: make(map[string]bool), objects: make(map[string][]byte)}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	s, err := NewS3(context.Background(), S3Config{
+		Endpoint:  strings.TrimPrefix(srv.URL, "http://"),
+		AccessKey: "test",
+		SecretKey: "testtesttest",
+		Bucket:    "attachments",
+		Region:    "us-east-1",
+	})
+	if err != nil {
+		t.Fatalf("NewS3: %v", err)
+	}
+	if !stub.buckets["attachments"] {
+		t.Fatal("NewS3 did not create the bucket")
+	}
+	testStorage(t, s)
+}
+
+func TestNewS3RequiresEndpointAndBucket(t *testing.T) {
+	if _, err := NewS3(context.Background(), S3Config{Bucket: "b"}); err == nil {
+		t.Error("NewS3 without endpoint succeeded")
+	}
+	if _, err := NewS3(context.Background(), S3Config{Endpoint: "localhost:9000"}); err == nil {
+		t.Error("NewS3 without bucket succeeded")
+	}
+}
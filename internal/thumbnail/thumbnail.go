@@ -0,0 +1,60 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// ContentType — тип, в котором Make кодирует миниатюры.
+const ContentType = "image/jpeg"
+
+// MaxPixels ограничивает размер исходного изображения: маленький файл может
+// объявить огромные размеры, и декодирование заняло бы гигабайты памяти.
+const MaxPixels = 50_000_000
+
+var ErrTooLarge = errors.New("thumbnail: image dimensions too large")
+
+// Make уменьшает изображение (JPEG, PNG, GIF или WebP) так, чтобы большая
+// сторона была не больше size, и кодирует его в JPEG. Маленькие изображения
+// не увеличиваются.
+func Make(data []byte, size int) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > MaxPixels/cfg.Height {
+		return nil, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	// Прозрачные области PNG/GIF в JPEG станут белыми, а не чёрными.
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
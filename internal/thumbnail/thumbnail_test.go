@@ -0,0 +1,74 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, x*h/w, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name         string
+		w, h, size   int
+		wantW, wantH int
+	}{
+		{"landscape", 800, 400, 200, 200, 100},
+		{"portrait", 300, 900, 300, 100, 300},
+		{"small stays", 50, 40, 200, 50, 40},
+		{"thin", 1000, 2, 100, 100, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := Make(encodePNG(t, tt.w, tt.h), tt.size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(out))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != "jpeg" || cfg.Width != tt.wantW || cfg.Height != tt.wantH {
+				t.Errorf("got %s %dx%d, want jpeg %dx%d", format, cfg.Width, cfg.Height, tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+// TestMakeRejectsHugeDimensions подменяет размеры в заголовке маленького PNG:
+// декодер поверил бы им и выделил память под всё изображение.
+func TestMakeRejectsHugeDimensions(t *testing.T) {
+	data := encodePNG(t, 10, 10)
+	// IHDR идёт сразу после 8-байтной сигнатуры: длина (4), тип (4), ширина, высота.
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:4], 100000)
+	binary.BigEndian.PutUint32(ihdr[4:8], 100000)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+
+	if _, err := Make(data, 200); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("Make() error = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestMakeRejectsGarbage(t *testing.T) {
+	if _, err := Make([]byte("not an image"), 200); err == nil {
+		t.Fatal("Make() accepted garbage")
+	}
+}